@@ -0,0 +1,210 @@
+package scrconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	goImage "image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/mrcook/scrconv/image"
+)
+
+// ImageToAPNG encodes the image as an animated PNG when the image has FLASH
+// attributes, otherwise as a plain PNG. Any text info of the image is stored
+// in tEXt chunks.
+func ImageToAPNG(w io.Writer, img *image.Image) error {
+	if !img.IsAnimated() {
+		return ImageToPNG(w, img)
+	}
+
+	flashFrame := func(i int) (goImage.Image, int) {
+		img.SetFlashOutput(i == 1)
+		return img.Render(), img.FlashDelay()
+	}
+
+	data, err := encodeAPNG(2, flashFrame, img.FlashLoopCount())
+	if err != nil {
+		return err
+	}
+	if len(img.Info()) > 0 {
+		data = pngWithInfo(data, img.Info())
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// AnimationToAPNG encodes the frames as an animated PNG, played the given
+// number of times, 0 to loop forever. Each frame is rendered only when it is
+// encoded, so only the compressed frames are kept in memory. When the frames
+// have different palettes, such as a ULAplus screen in a sequence, they are
+// all encoded as RGB images so they share the same colour model.
+func AnimationToAPNG(w io.Writer, frames []image.Frame, plays int) error {
+	sharedPalette := true
+	for _, frame := range frames {
+		if !samePalette(frame.Image.Palette(), frames[0].Image.Palette()) {
+			sharedPalette = false
+			break
+		}
+	}
+
+	animationFrame := func(i int) (goImage.Image, int) {
+		rendered := frames[i].Image.Render()
+		if !sharedPalette {
+			rgba := goImage.NewRGBA(rendered.Bounds())
+			draw.Draw(rgba, rgba.Rect, rendered, rendered.Bounds().Min, draw.Src)
+			rendered = rgba
+		}
+		return rendered, frames[i].Delay
+	}
+
+	data, err := encodeAPNG(len(frames), animationFrame, plays)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// samePalette reports whether the palettes have the same colours.
+func samePalette(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// apngFrame returns the image of the frame at the index of an animated PNG,
+// with its delay in 1/100s of a second. All frames must have the same size
+// and colour model.
+type apngFrame func(i int) (goImage.Image, int)
+
+// encodeAPNG encodes the frames as an animated PNG, played the given number
+// of times, 0 to loop forever. Each frame is encoded in turn with the Go PNG
+// encoder, the first frame's IDAT chunks being the default image, and those
+// of the following frames being rewritten as fdAT chunks. As all frames use
+// the header and palette of the first frame, an error is returned when the
+// size or colour model of a frame is different. Delays longer than the
+// maximum of 65535 are shortened to the maximum.
+func encodeAPNG(count int, frame apngFrame, plays int) ([]byte, error) {
+	if count == 0 {
+		return nil, errors.New("no APNG frames")
+	}
+
+	var out bytes.Buffer
+	var sequence uint32
+	var header, palette []byte
+
+	for i := 0; i < count; i++ {
+		frameImage, delay := frame(i)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frameImage); err != nil {
+			return nil, err
+		}
+		chunks, err := pngChunks(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		// the header and palette must match those of the first frame
+		var frameHeader, framePalette []byte
+		for _, chunk := range chunks {
+			switch chunk.chunkType {
+			case "IHDR":
+				frameHeader = chunk.data
+			case "PLTE":
+				framePalette = chunk.data
+			}
+		}
+		if i == 0 {
+			header, palette = frameHeader, framePalette
+		} else if !bytes.Equal(frameHeader, header) || !bytes.Equal(framePalette, palette) {
+			return nil, fmt.Errorf("APNG frame %d error, the size or colours differ from the first frame", i+1)
+		}
+
+		bounds := frameImage.Bounds()
+		fcTL := binary.BigEndian.AppendUint32(nil, sequence)
+		fcTL = binary.BigEndian.AppendUint32(fcTL, uint32(bounds.Dx()))
+		fcTL = binary.BigEndian.AppendUint32(fcTL, uint32(bounds.Dy()))
+		fcTL = binary.BigEndian.AppendUint32(fcTL, 0) // x offset
+		fcTL = binary.BigEndian.AppendUint32(fcTL, 0) // y offset
+		fcTL = binary.BigEndian.AppendUint16(fcTL, uint16(max(0, min(delay, math.MaxUint16))))
+		fcTL = binary.BigEndian.AppendUint16(fcTL, 100) // delay denominator: 1/100s
+		fcTL = append(fcTL, 0, 0)                       // dispose: none, blend: source
+		sequence++
+		frameControlWritten := false
+		for _, chunk := range chunks {
+			switch {
+			case chunk.chunkType == "IDAT" && i == 0:
+				if !frameControlWritten {
+					out.Write(pngChunk("fcTL", fcTL))
+					frameControlWritten = true
+				}
+				out.Write(pngChunk("IDAT", chunk.data))
+			case chunk.chunkType == "IDAT":
+				if !frameControlWritten {
+					out.Write(pngChunk("fcTL", fcTL))
+					frameControlWritten = true
+				}
+				fdAT := binary.BigEndian.AppendUint32(nil, sequence)
+				out.Write(pngChunk("fdAT", append(fdAT, chunk.data...)))
+				sequence++
+			case i > 0 || chunk.chunkType == "IEND":
+				// only the image data of the following frames is used,
+				// and the IEND chunk is written after all frames
+			case chunk.chunkType == "IHDR":
+				out.Write(pngSignature)
+				out.Write(pngChunk("IHDR", chunk.data))
+				acTL := binary.BigEndian.AppendUint32(nil, uint32(count))
+				acTL = binary.BigEndian.AppendUint32(acTL, uint32(plays))
+				out.Write(pngChunk("acTL", acTL))
+			default:
+				out.Write(pngChunk(chunk.chunkType, chunk.data))
+			}
+		}
+	}
+	out.Write(pngChunk("IEND", nil))
+
+	return out.Bytes(), nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunkData is the type and data of a chunk read from a PNG.
+type pngChunkData struct {
+	chunkType string
+	data      []byte
+}
+
+// pngChunks splits the encoded PNG into its chunks.
+func pngChunks(data []byte) ([]pngChunkData, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("invalid PNG signature")
+	}
+	data = data[len(pngSignature):]
+
+	var chunks []pngChunkData
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, errors.New("invalid PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data))
+		if len(data) < 12+length {
+			return nil, errors.New("invalid PNG chunk length")
+		}
+		chunks = append(chunks, pngChunkData{chunkType: string(data[4:8]), data: data[8 : 8+length]})
+		data = data[12+length:]
+	}
+	return chunks, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The input file types included when converting a directory.
+var batchExtensions = map[string]bool{
+	".scr": true, ".sna": true, ".z80": true, ".tap": true, ".tzx": true,
+}
+
+// batchResult is the outcome of converting one file of a batch.
+type batchResult struct {
+	filename string
+	images   int
+	err      error
+}
+
+// isBatch reports whether the input is a directory or a glob pattern.
+func isBatch(input string) bool {
+	if strings.ContainsAny(input, "*?[") {
+		return true
+	}
+	info, err := os.Stat(input)
+	return err == nil && info.IsDir()
+}
+
+// convertBatch converts all the input files using a pool of workers,
+// printing a summary of the results, and returns the number of failures.
+func convertBatch() int {
+	files, root, err := batchFiles(opts.InFilename, opts.Recursive)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("ERROR finding input files: %w", err))
+		return 1
+	}
+
+	clashes := clashingNames(files)
+
+	jobs := make(chan string)
+	results := make(chan batchResult)
+
+	workers := opts.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filename := range jobs {
+				results <- convertBatchFile(filename, root, clashes[filename])
+			}
+		}()
+	}
+
+	go func() {
+		for _, filename := range files {
+			jobs <- filename
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var failures []batchResult
+	converted, images := 0, 0
+	for result := range results {
+		if result.err != nil {
+			failures = append(failures, result)
+			continue
+		}
+		converted++
+		images += result.images
+	}
+
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].filename < failures[j].filename
+	})
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", failure.filename, failure.err)
+	}
+	fmt.Fprintf(os.Stderr, "%d files converted successfully (%d images), %d failed\n", converted, images, len(failures))
+
+	return len(failures)
+}
+
+// convertBatchFile converts a single file, with the output directory
+// mirroring the location of the file within the input root directory. When
+// another file has the same basename its extension is kept in the output name.
+func convertBatchFile(filename, root string, keepExtension bool) batchResult {
+	fileOpts := opts
+	fileOpts.InFilename = filename
+	fileOpts.KeepExtension = keepExtension
+
+	if len(opts.OutputDir) > 0 {
+		relative, err := filepath.Rel(root, filepath.Dir(filename))
+		if err != nil {
+			return batchResult{filename: filename, err: err}
+		}
+		fileOpts.OutputDir = filepath.Join(opts.OutputDir, relative)
+	}
+
+	images, err := convertFile(fileOpts)
+	return batchResult{filename: filename, images: images, err: err}
+}
+
+// batchFiles returns the files to convert, and the root directory used for
+// mirroring the directory tree. A directory input includes all the supported
+// file types, while a glob pattern includes the files matching the pattern.
+// Finding no files is an error, as is a recursive glob pattern with pattern
+// characters in its directory, which can't be matched in the subdirectories.
+func batchFiles(input string, recursive bool) ([]string, string, error) {
+	root := input
+	match := func(path string) bool {
+		return batchExtensions[strings.ToLower(filepath.Ext(path))]
+	}
+
+	if strings.ContainsAny(input, "*?[") {
+		root = globRoot(input)
+		pattern := filepath.Base(input)
+
+		if recursive && root != filepath.Dir(input) {
+			return nil, root, fmt.Errorf("recursive glob pattern must only match filenames: %s", input)
+		}
+		if !recursive {
+			files, err := filepath.Glob(input)
+			if err == nil && len(files) == 0 {
+				err = fmt.Errorf("no files match: %s", input)
+			}
+			return files, root, err
+		}
+		match = func(path string) bool {
+			ok, _ := filepath.Match(pattern, filepath.Base(path))
+			return ok
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if match(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err == nil && len(files) == 0 {
+		err = fmt.Errorf("no files found: %s", input)
+	}
+
+	return files, root, err
+}
+
+// clashingNames returns the files which share their directory and basename,
+// without the extension, with another file, such as game.sna and game.scr.
+func clashingNames(files []string) map[string]bool {
+	baseNames := map[string][]string{}
+	for _, filename := range files {
+		baseName := strings.ToLower(strings.TrimSuffix(filename, filepath.Ext(filename)))
+		baseNames[baseName] = append(baseNames[baseName], filename)
+	}
+
+	clashes := map[string]bool{}
+	for _, names := range baseNames {
+		if len(names) > 1 {
+			for _, filename := range names {
+				clashes[filename] = true
+			}
+		}
+	}
+	return clashes
+}
+
+// globRoot returns the directory of a glob pattern up to the first path
+// element containing a pattern character.
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// batchTree creates the files in a temporary directory, returning its path.
+func batchTree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBatchFiles(t *testing.T) {
+	dir := batchTree(t,
+		"game.scr", "game.sna", "notes.txt", "intro.TAP",
+		"sub/level.z80", "sub/level.txt", "sub/deep/title.scr", "docs/readme.txt",
+	)
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		recursive bool
+		files     []string
+		root      string
+	}{
+		{"directory", ".", false, []string{"game.scr", "game.sna", "intro.TAP"}, "."},
+		{"recursive directory", ".", true, []string{"game.scr", "game.sna", "intro.TAP", "sub/deep/title.scr", "sub/level.z80"}, "."},
+		{"glob", "*.scr", false, []string{"game.scr"}, "."},
+		{"recursive glob", "*.scr", true, []string{"game.scr", "sub/deep/title.scr"}, "."},
+		{"glob of a subdirectory", "sub/*.z80", false, []string{"sub/level.z80"}, "sub"},
+		{"directory glob", "*/*.z80", false, []string{"sub/level.z80"}, "."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			files, root, err := batchFiles(filepath.Join(dir, filepath.FromSlash(tc.input)), tc.recursive)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var expected []string
+			for _, name := range tc.files {
+				expected = append(expected, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+			if !reflect.DeepEqual(files, expected) {
+				t.Errorf("expected files %v, got %v", expected, files)
+			}
+			if expectedRoot := filepath.Join(dir, tc.root); root != expectedRoot {
+				t.Errorf("expected root %s, got %s", expectedRoot, root)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name      string
+		input     string
+		recursive bool
+	}{
+		{"no matching files", "*.tzx", false},
+		{"no matching files recursive", "*.tzx", true},
+		{"no supported files", "docs", false},
+		{"empty directory", "empty", false},
+		{"recursive directory glob", "*/*.z80", true},
+		{"missing directory", "missing", true},
+	}
+	for _, tc := range errorTests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := batchFiles(filepath.Join(dir, filepath.FromSlash(tc.input)), tc.recursive); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestClashingNames(t *testing.T) {
+	files := []string{
+		"game.scr", "game.sna", "GAME.z80",
+		"intro.tap",
+		"sub/game.scr",
+		"sub/title.scr", "other/title.scr",
+	}
+	expected := map[string]bool{"game.scr": true, "game.sna": true, "GAME.z80": true}
+
+	if clashes := clashingNames(files); !reflect.DeepEqual(clashes, expected) {
+		t.Errorf("expected clashes %v, got %v", expected, clashes)
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := []struct {
+		pattern string
+		root    string
+	}{
+		{"*.scr", "."},
+		{"games/*.scr", "games"},
+		{"games/a?/*.scr", "games"},
+		{"games/[a-z]*/sub/*.scr", "games"},
+		{"/path/to/games/*.scr", "/path/to/games"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern, func(t *testing.T) {
+			pattern, root := filepath.FromSlash(tc.pattern), filepath.FromSlash(tc.root)
+			if got := globRoot(pattern); got != root {
+				t.Errorf("expected %s, got %s", root, got)
+			}
+		})
+	}
+}
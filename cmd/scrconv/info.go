@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mrcook/scrconv"
+	"github.com/mrcook/scrconv/image"
+)
+
+// The ZX Spectrum colour names, with the bright colours being 8-15.
+var colourNames = []string{
+	"black", "blue", "red", "magenta", "green", "cyan", "yellow", "white",
+	"bright black", "bright blue", "bright red", "bright magenta",
+	"bright green", "bright cyan", "bright yellow", "bright white",
+}
+
+// fileStats are the statistics of a single file, for the JSON output.
+type fileStats struct {
+	File string `json:"file"`
+	*image.Stats
+}
+
+// runInfo is the info subcommand, which prints the statistics of each SCR
+// file, and returns the exit code.
+func runInfo(args []string) int {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Output the statistics as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s info [-json] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	results := []fileStats{}
+	exitCode := 0
+	for _, filename := range flags.Args() {
+		stats, err := readStats(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			exitCode = 1
+			continue
+		}
+		results = append(results, fileStats{File: filename, Stats: stats})
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Errorf("ERROR writing JSON: %w", err))
+			return 1
+		}
+		return exitCode
+	}
+
+	for _, result := range results {
+		printStats(result)
+	}
+	return exitCode
+}
+
+func readStats(filename string) (*image.Stats, error) {
+	reader, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR opening SCR file: %w", err)
+	}
+	defer reader.Close()
+
+	stats, err := scrconv.SCRStats(reader)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR reading SCR file: %w", err)
+	}
+	return stats, nil
+}
+
+func printStats(result fileStats) {
+	stats := result.Stats
+
+	var attributes []int
+	for attr := range stats.Attributes {
+		attributes = append(attributes, int(attr))
+	}
+	sort.Ints(attributes)
+
+	var attrCounts []string
+	for _, attr := range attributes {
+		attrCounts = append(attrCounts, fmt.Sprintf("0x%02X (%d)", attr, stats.Attributes[uint8(attr)]))
+	}
+
+	fmt.Println(result.File)
+	fmt.Printf("  sha256:        %s\n", stats.SHA256)
+	fmt.Printf("  attributes:    %d used: %s\n", len(attributes), strings.Join(attrCounts, ", "))
+	fmt.Printf("  ink colours:   %s\n", colourHistogram(stats.InkColours))
+	fmt.Printf("  paper colours: %s\n", colourHistogram(stats.PaperColours))
+	fmt.Printf("  flash cells:   %d\n", stats.FlashCells)
+	fmt.Printf("  bright cells:  %d\n", stats.BrightCells)
+	fmt.Printf("  empty cells:   %d\n", stats.EmptyCells)
+	fmt.Printf("  solid cells:   %d\n", stats.SolidCells)
+	fmt.Printf("  common colour: %d (%s)\n", stats.MostCommonColour, colourNames[stats.MostCommonColour])
+}
+
+// colourHistogram lists the colours used, with the number of cells for each.
+func colourHistogram(counts [16]int) string {
+	var colours []string
+	for colour, count := range counts {
+		if count > 0 {
+			colours = append(colours, fmt.Sprintf("%s (%d)", colourNames[colour], count))
+		}
+	}
+	return strings.Join(colours, ", ")
+}
@@ -3,94 +3,522 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/mrcook/scrconv"
+	"github.com/mrcook/scrconv/image"
 	"github.com/mrcook/scrconv/options"
 )
 
-var opts = options.Options{}
+var (
+	opts        = options.Options{}
+	showVersion bool
+	borderSize  string
+	hiResColour int
+	borderLines string
+
+	// the output files written, so two inputs never overwrite the same file
+	outputsMutex sync.Mutex
+	outputs      = map[string]bool{}
+)
 
 func init() {
 	flag.Usage = func() {
-		fmt.Printf("Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s info [-json] file...\n\tReport the statistics of SCR files\n", os.Args[0])
 		flag.PrintDefaults()
 		os.Exit(0)
 	}
 
-	flag.StringVar(&opts.InFilename, "scr", "", "Input .SCR filename")
-	flag.StringVar(&opts.ImageFormat, "format", "auto", "Image format: auto, gif, jpg, png (auto=png or gif when FLASH is detected")
-	flag.IntVar(&opts.Scale, "scale", 1, "Scale factor, max: 4, default: 1")
+	flag.StringVar(&opts.InFilename, "scr", "", "Input .SCR, .TAP/.TZX tape, or .SNA/.Z80 snapshot filename, directory, or glob pattern (- for stdin)")
+	flag.StringVar(&opts.OutFilename, "o", "", "Output filename, - for stdout (default: input filename with the format extension, or stdout for stdin)")
+	flag.StringVar(&opts.ScreenMode, "mode", "standard", "SCR screen mode: standard, hicolour (Timex 8x1 attributes), hires (Timex 512x192)")
+	flag.IntVar(&hiResColour, "hires-colour", -1, "Hi-res INK colour 0-7, the PAPER being its complement (default: from screen, or black on white)")
+	flag.BoolVar(&opts.Lenient, "lenient", false, "Pad truncated screens, as bitmap-only for missing attributes, and ignore any trailing data")
+	flag.BoolVar(&opts.Recursive, "recursive", false, "Include subdirectories when -scr is a directory or glob pattern")
+	flag.StringVar(&opts.OutputDir, "out-dir", "", "Output directory, mirroring the input directory tree (default: same as input)")
+	flag.IntVar(&opts.Workers, "workers", runtime.NumCPU(), "Number of files to convert concurrently")
+	flag.StringVar(&opts.ImgFilename, "img", "", "Input PNG, GIF or JPG filename to convert to a .SCR (- for stdin)")
+	flag.StringVar(&opts.Dither, "dither", "none", "Dithering for -img: none, bayer2, bayer4, bayer8, floyd-steinberg, atkinson")
+	flag.StringVar(&opts.ImageFormat, "format", "auto", "Image format: auto, apng, gif, jpg, png (auto=png or gif when FLASH is detected)")
+	flag.IntVar(&opts.FlashDelay, "flash-delay", 32, "Duration of each FLASH phase in 1/100s of a second (0.32s matches the real ULA)")
+	flag.IntVar(&opts.FlashLoopCount, "flash-loops", 0, "Number of times the FLASH animation is played, 0 loops forever")
+	flag.BoolVar(&opts.FlashStatic, "flash-static", false, "Output a single FLASH phase instead of an animation")
+	flag.IntVar(&opts.FlashPhase, "flash-phase", 0, "FLASH phase of a -flash-static image: 0 normal, 1 INK/PAPER swapped")
+	flag.BoolVar(&opts.Loading, "loading", false, "Output an animation of the SCR being loaded from tape, as a gif or apng")
+	flag.IntVar(&opts.LoadingSpeed, "loading-speed", 8, "How many times faster than a real Spectrum the loading animation is")
+	flag.BoolVar(&opts.Sequence, "sequence", false, "Output the screens of the -scr file, directory or glob, and any other SCR files given as arguments, as one animation")
+	flag.IntVar(&opts.FrameDelay, "frame-delay", 4, "Delay between animation frames in 1/100s of a second")
+	flag.IntVar(&opts.LoopCount, "loops", 0, "Number of times a -sequence animation is played, 0 loops forever")
+	flag.IntVar(&opts.Scale, "scale", 1, "Scale factor, default: 1")
+	flag.StringVar(&opts.Filter, "filter", "none", "Upscaling filter for the scale factor: none, epx, scalex (Scale2x/3x), xbr")
+	flag.StringVar(&opts.Aspect, "aspect", "square", "Pixel aspect ratio: square, pal (the pixel shape on a PAL TV)")
+	flag.StringVar(&opts.CRT, "crt", "none", "CRT emulation filter: none, scanlines, monitor, tv")
+	flag.IntVar(&opts.FitWidth, "fit-width", 0, "Resize the output to fit this width, keeping the aspect ratio")
+	flag.IntVar(&opts.FitHeight, "fit-height", 0, "Resize the output to fit this height, keeping the aspect ratio")
 	flag.BoolVar(&opts.WithBorder, "border", true, "Add a border to the image")
+	flag.StringVar(&borderSize, "border-size", "normal", "Border size: normal (320x240), full (352x296), frame (352x312), or custom left,right,top,bottom pixels")
 	flag.IntVar(&opts.BorderColour, "border-colour", 0, "Border Colour, values: 0 - 15 (default 0)")
+	flag.StringVar(&borderLines, "border-lines", "", "File of border colours (0-15) for each display line, repeated down the image")
+	flag.StringVar(&opts.BorderStripes, "border-stripes", "none", "Tape loading border stripes: none, pilot (red/cyan), data (blue/yellow)")
+	flag.Float64Var(&opts.StripeRandomness, "stripe-randomness", 0.2, "Variation of the border stripe lengths: 0 - 1")
+	flag.Int64Var(&opts.StripeSeed, "stripe-seed", 1, "Seed for the random data bits and variation of the border stripes")
 	flag.BoolVar(&opts.AutoBorderColour, "auto-border", false, "EXPERIMENTAL: Auto Detect Border Colour")
-	v := flag.Bool("v", false, "Show version number")
+	flag.StringVar(&opts.Palette, "palette", "default", "Colour palette: default, fuse, spectaculator, zesarux, wikipedia, hardware, or a GIMP .gpl/.json palette file")
+	flag.BoolVar(&showVersion, "v", false, "Show version number")
+}
 
+// parseFlags parses and validates the command line options, exiting on error.
+func parseFlags() {
 	flag.Parse()
 
-	if *v {
+	if showVersion {
 		fmt.Printf("%s v%s\n", os.Args[0], scrconv.Version)
 		os.Exit(0)
 	}
 
+	// -1 (the default) uses the screen's port 0xFF value
+	if hiResColour != -1 {
+		opts.HiResInk, opts.HiResColour = true, hiResColour
+	}
+
+	if err := parseBorderSize(); err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("ERROR invalid border size: %w", err))
+		os.Exit(2)
+	}
+
 	if err := opts.Validate(); err != nil {
-		fmt.Printf("ERROR invalid input\n%s", err)
-		fmt.Println()
-		fmt.Println()
+		fmt.Fprintf(os.Stderr, "ERROR invalid input\n%s", err)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr)
 		flag.Usage()
 		os.Exit(2)
 	}
+
+	if err := loadPalette(); err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("ERROR loading palette: %w", err))
+		os.Exit(2)
+	}
+
+	if err := loadBorderLines(); err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("ERROR loading border lines: %w", err))
+		os.Exit(2)
+	}
+}
+
+// parseBorderSize sets the border size option from either a preset name,
+// or the custom left,right,top,bottom sizes.
+func parseBorderSize() error {
+	if !strings.Contains(borderSize, ",") {
+		opts.BorderSize = borderSize
+		return nil
+	}
+
+	sides := strings.Split(borderSize, ",")
+	if len(sides) != 4 {
+		return fmt.Errorf("'%s' must have 4 sizes: left,right,top,bottom", borderSize)
+	}
+
+	var sizes [4]int
+	for i, side := range sides {
+		size, err := strconv.Atoi(strings.TrimSpace(side))
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", side)
+		}
+		sizes[i] = size
+	}
+
+	opts.BorderSize = "custom"
+	opts.BorderLeft, opts.BorderRight, opts.BorderTop, opts.BorderBottom = sizes[0], sizes[1], sizes[2], sizes[3]
+	return nil
+}
+
+// loadBorderLines reads the border colour of each line from the file given
+// in the border-lines flag.
+func loadBorderLines() error {
+	if len(borderLines) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(borderLines)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	opts.BorderLines, err = image.ReadBorderLines(file)
+	return err
+}
+
+// loadPalette registers a palette file given in the palette option, using
+// the filename as the palette name, otherwise checks the name is known.
+func loadPalette() error {
+	switch strings.ToLower(filepath.Ext(opts.Palette)) {
+	case ".gpl", ".json":
+		palette, err := image.LoadPaletteFile(opts.Palette)
+		if err != nil {
+			return err
+		}
+		image.RegisterPalette(opts.Palette, palette)
+		return nil
+	}
+
+	if _, ok := image.PaletteByName(opts.Palette); !ok {
+		names := image.PaletteNames()
+		sort.Strings(names)
+		return fmt.Errorf("unknown palette '%s', must be one of: %s", opts.Palette, strings.Join(names, ", "))
+	}
+	return nil
 }
 
 func main() {
-	reader, err := os.Open(opts.InFilename)
+	if len(os.Args) > 1 && os.Args[1] == "info" {
+		os.Exit(runInfo(os.Args[2:]))
+	}
+
+	parseFlags()
+
+	if len(opts.ImgFilename) > 0 {
+		if err := convertToSCR(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "image converted to SCR successfully")
+		return
+	}
+
+	if opts.Sequence {
+		if err := convertSequence(opts, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "SCR sequence converted successfully")
+		return
+	}
+
+	if isBatch(opts.InFilename) {
+		if len(opts.OutFilename) > 0 {
+			fmt.Fprintln(os.Stderr, "ERROR the -o option can not be used with a directory or glob pattern, use -out-dir")
+			os.Exit(2)
+		}
+		if failed := convertBatch(); failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	count, err := convertFile(opts)
 	if err != nil {
-		fmt.Println(fmt.Errorf("ERROR opening SCR file: %w", err))
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+
+	if count > 1 {
+		fmt.Fprintf(os.Stderr, "%d tape screens converted successfully\n", count)
+	} else {
+		fmt.Fprintln(os.Stderr, "SCR image converted successfully")
+	}
+}
+
+// convertFile converts the input file, returning the number of images written.
+func convertFile(o options.Options) (int, error) {
+	ext := strings.ToLower(filepath.Ext(o.InFilename))
+	if o.Loading {
+		if ext == ".tap" || ext == ".tzx" || ext == ".sna" || ext == ".z80" {
+			return 0, fmt.Errorf("ERROR the loading animation can only be made from a SCR file, not a %s file", ext)
+		}
+		return convertLoading(o)
+	}
+
+	if ext == ".tap" || ext == ".tzx" {
+		return convertTape(o)
+	}
+
+	reader, err := openInput(o.InFilename)
+	if err != nil {
+		return 0, fmt.Errorf("ERROR opening SCR file: %w", err)
+	}
 	defer reader.Close()
 
-	img, err := scrconv.ConvertToImage(reader, opts)
+	img, err := scrconv.ConvertToImage(reader, o)
 	if err != nil {
-		fmt.Println(fmt.Errorf("ERROR reading SCR file: %w", err))
-		os.Exit(1)
+		return 0, fmt.Errorf("ERROR reading SCR file: %w", err)
 	}
 
-	if opts.ImageFormat == "auto" {
-		if img.HasFlashingPixels() {
-			opts.ImageFormat = "gif"
-		} else {
-			opts.ImageFormat = "png"
+	o.ImageFormat = imageFormat(o, img)
+
+	if err := writeImage(img, o.OutputFilename(), o.ImageFormat); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func convertTape(o options.Options) (int, error) {
+	reader, err := openInput(o.InFilename)
+	if err != nil {
+		return 0, fmt.Errorf("ERROR opening tape file: %w", err)
+	}
+	defer reader.Close()
+
+	screens, err := scrconv.ConvertTapeToImages(reader, o)
+	if err != nil {
+		return 0, fmt.Errorf("ERROR reading tape file: %w", err)
+	}
+
+	if len(o.OutFilename) > 0 {
+		if len(screens) > 1 {
+			return 0, fmt.Errorf("ERROR the tape has %d screens, -o can only be used with a single screen", len(screens))
+		}
+		o.ImageFormat = imageFormat(o, screens[0].Image)
+		if err := writeImage(screens[0].Image, o.OutputFilename(), o.ImageFormat); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	usedNames := map[string]int{}
+
+	for i, screen := range screens {
+		name := screen.Name
+		if len(name) == 0 {
+			name = strconv.Itoa(i + 1)
+		}
+		// keep the filenames unique when the same name is used more than once
+		usedNames[name]++
+		if usedNames[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, usedNames[name])
+		}
+
+		tapeOpts := o
+		tapeOpts.ImageFormat = imageFormat(o, screen.Image)
+
+		if err := writeImage(screen.Image, tapeOpts.OutputFilenameFor(name), tapeOpts.ImageFormat); err != nil {
+			return i, err
 		}
 	}
 
-	writer, err := os.Create(opts.OutputFilename())
+	return len(screens), nil
+}
+
+// convertLoading writes an animation of the SCR being loaded from tape.
+func convertLoading(o options.Options) (int, error) {
+	reader, err := openInput(o.InFilename)
 	if err != nil {
-		fmt.Println(fmt.Errorf("ERROR creating PNG image file: %w", err))
-		os.Exit(1)
+		return 0, fmt.Errorf("ERROR opening SCR file: %w", err)
+	}
+	defer reader.Close()
+
+	frames, err := scrconv.LoadingAnimation(reader, o)
+	if err != nil {
+		return 0, fmt.Errorf("ERROR reading SCR file: %w", err)
+	}
+
+	if o.ImageFormat == "auto" {
+		o.ImageFormat = "gif"
+	}
+	if err := writeAnimation(frames, 1, o.OutputFilename(), o.ImageFormat); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// convertSequence writes an animation of the screens of the input file, or
+// the SCR files of a directory or glob pattern, followed by any other files
+// given as arguments, in order.
+func convertSequence(o options.Options, others []string) error {
+	filenames := []string{o.InFilename}
+	if isBatch(o.InFilename) {
+		if len(o.OutFilename) == 0 {
+			return fmt.Errorf("ERROR the -o option is required for a sequence from a directory or glob pattern")
+		}
+		files, _, err := batchFiles(o.InFilename, o.Recursive)
+		if err != nil {
+			return fmt.Errorf("ERROR finding input files: %w", err)
+		}
+		filenames = filenames[:0]
+		for _, filename := range files {
+			if strings.ToLower(filepath.Ext(filename)) == ".scr" {
+				filenames = append(filenames, filename)
+			}
+		}
+	}
+	filenames = append(filenames, others...)
+	if len(filenames) == 0 {
+		return fmt.Errorf("ERROR no SCR files found for the sequence")
+	}
+
+	var files [][]byte
+	for _, filename := range filenames {
+		data, err := readInput(filename)
+		if err != nil {
+			return fmt.Errorf("ERROR reading SCR file: %w", err)
+		}
+		files = append(files, data)
+	}
+
+	frames, err := scrconv.SequenceAnimation(files, o)
+	if err != nil {
+		return fmt.Errorf("ERROR reading SCR file: %w", err)
+	}
+
+	if o.ImageFormat == "auto" {
+		o.ImageFormat = "gif"
+	}
+	return writeAnimation(frames, o.LoopCount, o.OutputFilename(), o.ImageFormat)
+}
+
+func convertToSCR() error {
+	reader, err := openInput(opts.ImgFilename)
+	if err != nil {
+		return fmt.Errorf("ERROR opening image file: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := scrconv.ImageToSCR(reader, opts)
+	if err != nil {
+		return fmt.Errorf("ERROR converting image to SCR: %w", err)
+	}
+
+	writer, err := createOutput(opts.OutputFilename())
+	if err != nil {
+		return fmt.Errorf("ERROR creating SCR file: %w", err)
+	}
+	defer writer.Close()
+
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("ERROR writing SCR file: %w", err)
+	}
+	return nil
+}
+
+// openInput opens the named file for reading, with "-" being stdin.
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+// readInput reads all the data of the named file, or stdin for "-", closing
+// the file once read.
+func readInput(filename string) ([]byte, error) {
+	reader, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// createOutput creates the named file, and any missing directories, with
+// "-" being stdout.
+func createOutput(filename string) (io.WriteCloser, error) {
+	if filename == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	if err := claimOutput(filename); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(filename)
+}
+
+// claimOutput records the output filename as written, returning an error
+// when it has already been written, such as by another file of a batch,
+// instead of overwriting it.
+func claimOutput(filename string) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+	if outputs[path] {
+		return fmt.Errorf("%s has already been written by another input", filename)
+	}
+	outputs[path] = true
+	return nil
+}
+
+// nopWriteCloser stops stdout from being closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// imageFormat returns the output format for the image, resolving the auto
+// format to a GIF when the FLASH animation is output, otherwise a PNG.
+func imageFormat(o options.Options, img *image.Image) string {
+	if o.ImageFormat != "auto" {
+		return o.ImageFormat
+	}
+	if img.IsAnimated() {
+		return "gif"
+	}
+	return "png"
+}
+
+// writeAnimation writes the frames as an animated GIF or APNG, played the
+// given number of times, 0 to loop forever.
+func writeAnimation(frames []image.Frame, plays int, filename, format string) error {
+	writer, err := createOutput(filename)
+	if err != nil {
+		return fmt.Errorf("ERROR creating image file: %w", err)
 	}
 	defer writer.Close()
 
-	switch opts.ImageFormat {
+	switch format {
+	case "gif":
+		err = scrconv.AnimationToGIF(writer, frames, plays)
+	case "apng":
+		err = scrconv.AnimationToAPNG(writer, frames, plays)
+	default:
+		return fmt.Errorf("invalid animation format selected")
+	}
+	if err != nil {
+		return fmt.Errorf("ERROR writing %s animation: %w", format, err)
+	}
+	return nil
+}
+
+func writeImage(img *image.Image, filename, format string) error {
+	writer, err := createOutput(filename)
+	if err != nil {
+		return fmt.Errorf("ERROR creating image file: %w", err)
+	}
+	defer writer.Close()
+
+	switch format {
 	case "png":
 		if err := scrconv.ImageToPNG(writer, img); err != nil {
-			fmt.Println(fmt.Errorf("ERROR convert SCR to PNG image: %w", err))
-			os.Exit(1)
+			return fmt.Errorf("ERROR convert SCR to PNG image: %w", err)
+		}
+	case "apng":
+		if err := scrconv.ImageToAPNG(writer, img); err != nil {
+			return fmt.Errorf("ERROR convert SCR to APNG image: %w", err)
 		}
 	case "jpg":
 		if err := scrconv.ImageToJPG(writer, img, 100); err != nil {
-			fmt.Println(fmt.Errorf("ERROR convert SCR to JPG image: %w", err))
-			os.Exit(1)
+			return fmt.Errorf("ERROR convert SCR to JPG image: %w", err)
 		}
 	case "gif":
 		if err := scrconv.ImageToGIF(writer, img); err != nil {
-			fmt.Println(fmt.Errorf("ERROR convert SCR to GIF image: %w", err))
-			os.Exit(1)
+			return fmt.Errorf("ERROR convert SCR to GIF image: %w", err)
 		}
 	default:
-		fmt.Println("invalid format selected")
-		os.Exit(1)
+		return fmt.Errorf("invalid format selected")
 	}
 
-	fmt.Println("SCR image converted successfully")
+	return nil
 }
@@ -0,0 +1,70 @@
+package image
+
+import "github.com/mrcook/scrconv/options"
+
+// borderSize is the size in pixels of each side of the border, for a
+// standard screen at scale 1.
+type borderSize struct {
+	left, right, top, bottom int
+}
+
+// borderPresets are the border geometry presets, for the visible area shown
+// by emulators and real hardware, with the normal size first.
+var borderPresets = []struct {
+	name string
+	size borderSize
+}{
+	{"normal", borderSize{32, 32, 24, 24}}, // 320x240, as shown by most emulators
+	{"full", borderSize{48, 48, 48, 56}},   // 352x296, the visible area of a 48K on a PAL TV
+	{"frame", borderSize{48, 48, 64, 56}},  // 352x312, all lines of a 48K frame, including those hidden by the vertical blanking
+}
+
+// borderSizeFor returns the border size of the options: the custom sizes,
+// the named preset, or the normal size by default.
+func borderSizeFor(opts options.Options) borderSize {
+	switch {
+	case !opts.WithBorder:
+		return borderSize{}
+	case opts.BorderSize == "custom":
+		return customBorderSize(opts)
+	}
+
+	for _, preset := range borderPresets {
+		if preset.name == opts.BorderSize {
+			return preset.size
+		}
+	}
+	return borderPresets[0].size
+}
+
+// customBorderSize returns the custom border sizes of the options.
+func customBorderSize(opts options.Options) borderSize {
+	return borderSize{opts.BorderLeft, opts.BorderRight, opts.BorderTop, opts.BorderBottom}
+}
+
+// knownBorderSizes returns the border sizes which can be removed from an
+// image: any custom sizes of the options, followed by the presets in order.
+func knownBorderSizes(opts options.Options) []borderSize {
+	var sizes []borderSize
+	if opts.BorderSize == "custom" {
+		sizes = append(sizes, customBorderSize(opts))
+	}
+	for _, preset := range borderPresets {
+		sizes = append(sizes, preset.size)
+	}
+	return sizes
+}
+
+// scaledBorder returns the border size with scaling applied, with the side
+// borders of a hi-res screen being doubled to match its pixel width.
+func (img *Image) scaledBorder() borderSize {
+	xScale := img.width / defaultWidth * img.scale
+	yScale := img.pixelHeight * img.scale
+
+	return borderSize{
+		left:   img.border.left * xScale,
+		right:  img.border.right * xScale,
+		top:    img.border.top * yScale,
+		bottom: img.border.bottom * yScale,
+	}
+}
@@ -19,37 +19,61 @@ type Colour struct {
 	// UseFlashColour will output RGBA colour with the ink/paper swapped,
 	// but only if the FLASH bit is also set
 	UseFlashColour bool
+
+	// ULAplus is the palette of a ULAplus screen, and when set the FLASH
+	// and BRIGHT bits select the palette group for the INK/PAPER colours.
+	ULAplus *ULAplusPalette
+
+	// Palette of the 16 ZX Spectrum colours, when nil the default is used.
+	Palette *Palette
 }
 
 // RGBA returns the RGBA colours, and respects the Go color.Color interface.
 func (c Colour) RGBA() (r, g, b, a uint32) {
-	ink, paper, flash := c.parseAttr()
-
-	// swap the ink/paper colours if enabled
-	if c.UseFlashColour && flash {
-		ink, paper = paper, ink
-	}
+	index := c.index()
 
 	// set correct pixel colour
-	var col rgb
-	if c.IsPixel {
-		col = sinclairColourMap[ink]
+	var col color.RGBA
+	if c.ULAplus != nil {
+		col = c.ULAplus.rgba(index)
+	} else if c.Palette != nil {
+		col = c.Palette[index]
 	} else {
-		col = sinclairColourMap[paper]
+		col = sinclairColourMap[index]
 	}
 
 	// now generate the RGBA value
-	r = uint32(col.r)
+	r = uint32(col.R)
 	r |= r << 8
-	g = uint32(col.g)
+	g = uint32(col.G)
 	g |= g << 8
-	b = uint32(col.b)
+	b = uint32(col.B)
 	b |= b << 8
 	a = uint32(0xFF) // the ZX Spectrum does not have transparency
 	a |= a << 8
 	return
 }
 
+// index returns the palette index of the colour: 0-15 for a ZX Spectrum
+// palette, or 0-63 for a ULAplus palette.
+func (c Colour) index() uint8 {
+	if c.ULAplus != nil {
+		return ulaplusIndex(c.ATTR, c.IsPixel)
+	}
+
+	ink, paper, flash := c.parseAttr()
+
+	// swap the ink/paper colours if enabled
+	if c.UseFlashColour && flash {
+		ink, paper = paper, ink
+	}
+
+	if c.IsPixel {
+		return ink
+	}
+	return paper
+}
+
 // extracts the relevant colour data from the attribute byte.
 func (c Colour) parseAttr() (uint8, uint8, bool) {
 	flash := c.ATTR&0b10000000 != 0     // the FLASH flag
@@ -66,37 +90,68 @@ func (c Colour) parseAttr() (uint8, uint8, bool) {
 	return ink, paper, flash
 }
 
-// RGB represent a ZX Spectrum colour.
-type rgb struct {
-	r, g, b uint8
+// ULAplusPalette is the 64 colour palette of the ULAplus, made up of four
+// groups of 8 INK followed by 8 PAPER colours. Each colour is a GRB332 byte.
+type ULAplusPalette [64]uint8
+
+// ulaplusIndex returns the palette index for the INK or PAPER of the
+// attribute, with the FLASH and BRIGHT bits selecting the palette group.
+func ulaplusIndex(attr uint8, isPixel bool) uint8 {
+	index := (attr >> 6) * 16
+	if isPixel {
+		return index + attr&0b00000111
+	}
+	return index + 8 + (attr&0b00111000)>>3
+}
+
+// Colors returns the palette as a Go color.Palette.
+func (p *ULAplusPalette) Colors() color.Palette {
+	var colours color.Palette
+	for i := range p {
+		colours = append(colours, p.rgba(uint8(i)))
+	}
+	return colours
+}
+
+// rgba returns the RGB colour of the palette entry.
+func (p *ULAplusPalette) rgba(index uint8) color.RGBA {
+	grb := p[index]
+	green := (grb >> 5) & 0b111
+	red := (grb >> 2) & 0b111
+	blue := grb & 0b11
+	blue = blue<<1 | (blue>>1 | blue&1) // the low bit is the OR of the two blue bits
+
+	return color.RGBA{R: expand3Bits(red), G: expand3Bits(green), B: expand3Bits(blue), A: 0xFF}
+}
+
+// expand3Bits scales a 3-bit colour value to 8-bits.
+func expand3Bits(v uint8) uint8 {
+	return v<<5 | v<<2 | v>>1
 }
 
 // The ZX Spectrum's 15 colours (normal & bright) mapped to RGB values.
-var sinclairColourMap = map[uint8]rgb{
+var sinclairColourMap = Palette{
 	// normal colours
-	0x00: {0x00, 0x00, 0x00},
-	0x01: {0x00, 0x00, 0xEE},
-	0x02: {0xEE, 0x00, 0x00},
-	0x03: {0xEE, 0x00, 0xEE},
-	0x04: {0x00, 0xEE, 0x00},
-	0x05: {0x00, 0xEE, 0xEE},
-	0x06: {0xEE, 0xEE, 0x00},
-	0x07: {0xEE, 0xEE, 0xEE},
+	{0x00, 0x00, 0x00, 0xFF},
+	{0x00, 0x00, 0xEE, 0xFF},
+	{0xEE, 0x00, 0x00, 0xFF},
+	{0xEE, 0x00, 0xEE, 0xFF},
+	{0x00, 0xEE, 0x00, 0xFF},
+	{0x00, 0xEE, 0xEE, 0xFF},
+	{0xEE, 0xEE, 0x00, 0xFF},
+	{0xEE, 0xEE, 0xEE, 0xFF},
 	// bright colours
-	0x08: {0x00, 0x00, 0x00},
-	0x09: {0x00, 0x00, 0xFF},
-	0x0A: {0xFF, 0x00, 0x00},
-	0x0B: {0xFF, 0x00, 0xFF},
-	0x0C: {0x00, 0xFF, 0x00},
-	0x0D: {0x00, 0xFF, 0xFF},
-	0x0E: {0xFF, 0xFF, 0x00},
-	0x0F: {0xFF, 0xFF, 0xFF},
+	{0x00, 0x00, 0x00, 0xFF},
+	{0x00, 0x00, 0xFF, 0xFF},
+	{0xFF, 0x00, 0x00, 0xFF},
+	{0xFF, 0x00, 0xFF, 0xFF},
+	{0x00, 0xFF, 0x00, 0xFF},
+	{0x00, 0xFF, 0xFF, 0xFF},
+	{0xFF, 0xFF, 0x00, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
 }
 
+// SpectrumPalette returns the colours of the default palette.
 func SpectrumPalette() []color.Color {
-	var colours []color.Color
-	for _, colour := range sinclairColourMap {
-		colours = append(colours, color.RGBA{R: colour.r, G: colour.g, B: colour.b, A: 0xff})
-	}
-	return colours
+	return sinclairColourMap.Colors()
 }
@@ -95,3 +95,33 @@ func TestColour_FlashFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestColour_ULAplus(t *testing.T) {
+	palette := image.ULAplusPalette{}
+	palette[2] = 0b00011100      // group 0, ink 2: full red
+	palette[16+8+5] = 0b11100011 // group 1, paper 5: full green and blue
+	palette[48+7] = 0b01001010   // group 3, ink 7: G=2, R=2, B=2
+	palette[48+8+0] = 0b00000001 // group 3, paper 0: B=1
+
+	table := []struct {
+		name    string
+		attr    uint8
+		pixel   bool
+		r, g, b uint32
+	}{
+		{"group 0 ink", 0b00000010, true, 0xFFFF, 0x0000, 0x0000},
+		{"group 1 paper", 0b01101000, false, 0x0000, 0xFFFF, 0xFFFF},
+		{"group 3 ink", 0b11000111, true, 0x4949, 0x4949, 0xB6B6},
+		{"group 3 paper", 0b11000111, false, 0x0000, 0x0000, 0x6D6D},
+	}
+
+	for i, col := range table {
+		t.Run(fmt.Sprintf("%02d %s", i+1, col.name), func(t *testing.T) {
+			colour := image.Colour{ATTR: col.attr, IsPixel: col.pixel, UseFlashColour: true, ULAplus: &palette}
+			r, g, b, a := colour.RGBA()
+			if r != col.r || g != col.g || b != col.b || a != 0xFFFF {
+				t.Errorf("mismatch RGBA, got: %04X, %04X, %04X, %04X", r, g, b, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// crtSettings are the strengths of the effects of the CRT filter, with 0
+// disabling an effect.
+type crtSettings struct {
+	scanlines    float64 // darkness of the gaps between the scanlines: 0-1
+	mask         string  // phosphor mask: shadow (triads), or grille (aperture grille stripes)
+	maskStrength float64 // darkness of the other colours of each mask phosphor: 0-1
+	bleed        float64 // horizontal PAL colour bleed, in screen pixels
+	bloom        float64 // glow around the bright areas: 0-1
+	curvature    float64 // barrel distortion of the screen: 0-0.25
+}
+
+// crtPresets are the named CRT filter settings.
+var crtPresets = map[string]crtSettings{
+	"scanlines": {scanlines: 0.5},
+	"monitor":   {scanlines: 0.3, mask: "grille", maskStrength: 0.2, bloom: 0.15},
+	"tv":        {scanlines: 0.4, mask: "shadow", maskStrength: 0.25, bleed: 1.5, bloom: 0.25, curvature: 0.06},
+}
+
+// crt applies the CRT filter settings to the image, with columns and lines
+// being the number of screen pixels across, and scanlines down, the image.
+func crt(src *image.RGBA, columns, lines int, settings crtSettings) *image.RGBA {
+	// the width of a screen pixel, and height of a scanline, in the output image
+	pixelWidth := float64(src.Rect.Dx()) / float64(columns)
+	lineHeight := float64(src.Rect.Dy()) / float64(lines)
+
+	out := src
+	if settings.bleed > 0 {
+		out = colourBleed(out, int(math.Round(settings.bleed*pixelWidth)))
+	}
+	if settings.bloom > 0 {
+		out = bloom(out, int(math.Round(2*lineHeight)), settings.bloom)
+	}
+	// scanlines need at least 2 output lines for each, otherwise the gaps can not be shown
+	if settings.scanlines > 0 && lineHeight >= 2 {
+		out = scanlines(out, lineHeight, settings.scanlines)
+	}
+	if settings.mask != "" && settings.maskStrength > 0 {
+		out = phosphorMask(out, settings.mask, settings.maskStrength)
+	}
+	if settings.curvature > 0 {
+		out = curve(out, settings.curvature)
+	}
+	return out
+}
+
+// scanlines darkens the gaps between the scanlines, with each line being
+// brightest at its centre.
+func scanlines(src *image.RGBA, lineHeight, strength float64) *image.RGBA {
+	dst := image.NewRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		// position within the scanline: 0-1
+		pos := math.Mod((float64(y)+0.5)/lineHeight, 1)
+		brightness := 1 - strength*(0.5+0.5*math.Cos(2*math.Pi*pos))
+
+		for x := 0; x < src.Rect.Dx(); x++ {
+			dst.SetRGBA(x, y, scaleRGBA(src.RGBAAt(x, y), brightness, brightness, brightness))
+		}
+	}
+	return dst
+}
+
+// phosphorMask multiplies each pixel by the colour of its phosphor, with an
+// aperture grille using vertical red/green/blue stripes, and a shadow mask
+// using triads which are offset on alternate rows.
+func phosphorMask(src *image.RGBA, mask string, strength float64) *image.RGBA {
+	dim := 1 - strength
+
+	dst := image.NewRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			phosphor := x % 3
+			if mask == "shadow" && (y/2)%2 == 1 {
+				phosphor = (x + 1) % 3
+			}
+
+			r, g, b := dim, dim, dim
+			switch phosphor {
+			case 0:
+				r = 1
+			case 1:
+				g = 1
+			case 2:
+				b = 1
+			}
+			dst.SetRGBA(x, y, scaleRGBA(src.RGBAAt(x, y), r, g, b))
+		}
+	}
+	return dst
+}
+
+// colourBleed blurs the colour, but not the brightness, of the pixels
+// horizontally, as with the limited chroma bandwidth of a PAL signal.
+func colourBleed(src *image.RGBA, radius int) *image.RGBA {
+	if radius < 1 {
+		return src
+	}
+
+	dst := image.NewRGBA(src.Rect)
+	width := src.Rect.Dx()
+
+	ys := make([]float64, width)
+	us := make([]float64, width)
+	vs := make([]float64, width)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < width; x++ {
+			ys[x], us[x], vs[x] = yuv(src.RGBAAt(x, y))
+		}
+		for x := 0; x < width; x++ {
+			var u, v float64
+			for i := x - radius; i <= x+radius; i++ {
+				i := max(0, min(i, width-1))
+				u += us[i]
+				v += vs[i]
+			}
+			u /= float64(radius*2 + 1)
+			v /= float64(radius*2 + 1)
+			dst.SetRGBA(x, y, fromYUV(ys[x], u, v))
+		}
+	}
+	return dst
+}
+
+// bloom adds a glow around the bright areas of the image, by adding a
+// blurred copy of the pixels brighter than a threshold.
+func bloom(src *image.RGBA, radius int, strength float64) *image.RGBA {
+	const threshold = 0x80
+
+	bright := image.NewRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			c := src.RGBAAt(x, y)
+			if luma, _, _ := yuv(c); luma > threshold {
+				bright.SetRGBA(x, y, c)
+			}
+		}
+	}
+	glow := boxBlur(bright, max(1, radius))
+
+	dst := image.NewRGBA(src.Rect)
+	for i := 0; i < len(src.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := float64(src.Pix[i+c]) + float64(glow.Pix[i+c])*strength
+			dst.Pix[i+c] = uint8(min(v, 0xFF))
+		}
+		dst.Pix[i+3] = 0xFF
+	}
+	return dst
+}
+
+// boxBlur blurs the image horizontally and then vertically by the radius.
+func boxBlur(src *image.RGBA, radius int) *image.RGBA {
+	blurred := src
+	for _, vertical := range []bool{false, true} {
+		dst := image.NewRGBA(src.Rect)
+		for y := 0; y < src.Rect.Dy(); y++ {
+			for x := 0; x < src.Rect.Dx(); x++ {
+				var r, g, b float64
+				for i := -radius; i <= radius; i++ {
+					var c color.RGBA
+					if vertical {
+						c = at(blurred, x, y+i)
+					} else {
+						c = at(blurred, x+i, y)
+					}
+					r += float64(c.R)
+					g += float64(c.G)
+					b += float64(c.B)
+				}
+				n := float64(radius*2 + 1)
+				dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xFF})
+			}
+		}
+		blurred = dst
+	}
+	return blurred
+}
+
+// curve applies a barrel distortion, as with the curved glass of a CRT,
+// with the corners outside the screen being black.
+func curve(src *image.RGBA, amount float64) *image.RGBA {
+	width, height := float64(src.Rect.Dx()), float64(src.Rect.Dy())
+
+	dst := image.NewRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			// the position from the centre: -1 to 1
+			cx := (float64(x)+0.5)/width*2 - 1
+			cy := (float64(y)+0.5)/height*2 - 1
+
+			sx := cx * (1 + amount*cy*cy)
+			sy := cy * (1 + amount*cx*cx)
+			if sx < -1 || sx > 1 || sy < -1 || sy > 1 {
+				dst.SetRGBA(x, y, color.RGBA{A: 0xFF})
+				continue
+			}
+
+			// bilinear sample of the source position
+			px := (sx+1)/2*width - 0.5
+			py := (sy+1)/2*height - 0.5
+			x0, y0 := int(math.Floor(px)), int(math.Floor(py))
+			fx, fy := px-float64(x0), py-float64(y0)
+
+			top := blend(at(src, x0, y0), at(src, x0+1, y0), fx)
+			bottom := blend(at(src, x0, y0+1), at(src, x0+1, y0+1), fx)
+			dst.SetRGBA(x, y, blend(top, bottom, fy))
+		}
+	}
+	return dst
+}
+
+// scaleRGBA multiplies the colour channels by the given amounts.
+func scaleRGBA(c color.RGBA, r, g, b float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R)*r + 0.5),
+		G: uint8(float64(c.G)*g + 0.5),
+		B: uint8(float64(c.B)*b + 0.5),
+		A: 0xFF,
+	}
+}
+
+// fromYUV returns the RGB colour of the YUV components, as given by yuv.
+func fromYUV(y, u, v float64) color.RGBA {
+	clamp := func(c float64) uint8 {
+		return uint8(max(0, min(c+0.5, 0xFF)))
+	}
+
+	r := y + v/0.877
+	b := y + u/0.492
+	g := (y - 0.299*r - 0.114*b) / 0.587
+	return color.RGBA{R: clamp(r), G: clamp(g), B: clamp(b), A: 0xFF}
+}
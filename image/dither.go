@@ -0,0 +1,146 @@
+package image
+
+import "fmt"
+
+// A ditherer decides which pixels of the screen are set to the INK colour,
+// choosing for each pixel between the two colours of its character cell.
+type ditherer func(screen *screenPixels, colours *cellColours) *inkPixels
+
+// newDitherer returns the dithering function for the named method.
+func newDitherer(method string) (ditherer, error) {
+	switch method {
+	case "", "none":
+		return ditherNearest, nil
+	case "bayer2":
+		return orderedDitherer(bayerMatrix(2)), nil
+	case "bayer4":
+		return orderedDitherer(bayerMatrix(4)), nil
+	case "bayer8":
+		return orderedDitherer(bayerMatrix(8)), nil
+	case "floyd-steinberg":
+		return errorDiffusionDitherer(floydSteinberg), nil
+	case "atkinson":
+		return errorDiffusionDitherer(atkinson), nil
+	default:
+		return nil, fmt.Errorf("unknown dithering method: %s", method)
+	}
+}
+
+// ditherNearest sets each pixel to the nearest of its cell's two colours.
+func ditherNearest(screen *screenPixels, colours *cellColours) *inkPixels {
+	ink := &inkPixels{}
+	for y := range screen {
+		for x, px := range screen[y] {
+			inkColour, paperColour := colours.at(x, y)
+			ink[y][x] = px.distance(inkColour) < px.distance(paperColour)
+		}
+	}
+	return ink
+}
+
+// bayerMatrix returns a size x size threshold matrix (size being a power of
+// two) with values normalised to the 0-1 range.
+func bayerMatrix(size int) [][]float64 {
+	matrix := [][]int{{0}}
+	for n := 1; n < size; n *= 2 {
+		next := make([][]int, n*2)
+		for y := range next {
+			next[y] = make([]int, n*2)
+			for x := range next[y] {
+				v := matrix[y%n][x%n] * 4
+				switch {
+				case y < n && x >= n:
+					v += 2
+				case y >= n && x < n:
+					v += 3
+				case y >= n && x >= n:
+					v += 1
+				}
+				next[y][x] = v
+			}
+		}
+		matrix = next
+	}
+
+	thresholds := make([][]float64, size)
+	for y := range matrix {
+		thresholds[y] = make([]float64, size)
+		for x, v := range matrix[y] {
+			thresholds[y][x] = (float64(v) + 0.5) / float64(size*size)
+		}
+	}
+	return thresholds
+}
+
+// orderedDitherer sets a pixel to INK when its position between the PAPER
+// and INK colours is above the threshold value of the matrix.
+func orderedDitherer(matrix [][]float64) ditherer {
+	size := len(matrix)
+
+	return func(screen *screenPixels, colours *cellColours) *inkPixels {
+		ink := &inkPixels{}
+		for y := range screen {
+			for x, px := range screen[y] {
+				inkColour, paperColour := colours.at(x, y)
+				ink[y][x] = inkAmount(inkColour, paperColour, px) > matrix[y%size][x%size]
+			}
+		}
+		return ink
+	}
+}
+
+// diffusion is the share of the quantisation error passed to a neighbouring pixel.
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+var floydSteinberg = []diffusion{
+	{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+}
+
+// atkinson only diffuses 3/4 of the error, giving higher contrast results.
+var atkinson = []diffusion{
+	{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8},
+}
+
+// errorDiffusionDitherer sets each pixel to the nearest of its cell's two
+// colours, passing the difference on to the neighbouring pixels, which may
+// be in a different cell.
+func errorDiffusionDitherer(kernel []diffusion) ditherer {
+	return func(screen *screenPixels, colours *cellColours) *inkPixels {
+		ink := &inkPixels{}
+		pixels := *screen // the errors are added to a copy of the screen
+
+		for y := range pixels {
+			for x := range pixels[y] {
+				px := pixels[y][x]
+				px = rgbf{clamp(px.r), clamp(px.g), clamp(px.b)}
+
+				inkColour, paperColour := colours.at(x, y)
+				chosen := paperColour
+				if px.distance(inkColour) < px.distance(paperColour) {
+					chosen = inkColour
+					ink[y][x] = true
+				}
+
+				diff := rgbf{px.r - chosen.r, px.g - chosen.g, px.b - chosen.b}
+				for _, d := range kernel {
+					nx, ny := x+d.dx, y+d.dy
+					if nx < 0 || nx >= defaultWidth || ny >= defaultHeight {
+						continue
+					}
+					pixels[ny][nx].r += diff.r * d.weight
+					pixels[ny][nx].g += diff.g * d.weight
+					pixels[ny][nx].b += diff.b * d.weight
+				}
+			}
+		}
+		return ink
+	}
+}
+
+// clamp limits a colour component to the 0-255 range.
+func clamp(v float64) float64 {
+	return max(0, min(255, v))
+}
@@ -0,0 +1,235 @@
+package image
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// ToSCR converts an image to the raw 6912 bytes of a ZX Spectrum SCR file.
+//
+// The image is first resampled to 256x192 pixels, with any border removed
+// when the image has the dimensions of a screen with the custom border sizes
+// of the options, or one of the border size presets (320x240, 640x480,
+// 352x296, etc.), then each 8x8 character cell is quantised to the INK,
+// PAPER and BRIGHT combination that best matches the original pixels. The
+// pixels of each cell are then set using the selected dithering method.
+func ToSCR(src image.Image, opts options.Options) ([]byte, error) {
+	if src.Bounds().Empty() {
+		return nil, errors.New("image contains no pixels")
+	}
+
+	ditherer, err := newDitherer(opts.Dither)
+	if err != nil {
+		return nil, err
+	}
+	screen := newScreenPixels(src, knownBorderSizes(opts))
+
+	// dithered pixels are a mix of the two cell colours, so are matched
+	// against the range of colours between the INK and the PAPER.
+	mixed := opts.Dither != "" && opts.Dither != "none"
+
+	colours := cellColours{palette: paletteFor(opts.Palette)}
+	for row := range colours.cells {
+		for col := range colours.cells[row] {
+			colours.cells[row][col] = bestCellColour(screen, col, row, mixed, colours.palette)
+		}
+	}
+
+	ink := ditherer(screen, &colours)
+
+	s := scr{}
+	for row := range colours.cells {
+		for col := range colours.cells[row] {
+			s.encodeCell(ink, colours.cells[row][col], col, row)
+		}
+	}
+
+	return s.bytes(), nil
+}
+
+// rgbf is an RGB colour with float components, which allows colours to be
+// averaged when resampling an image.
+type rgbf struct {
+	r, g, b float64
+}
+
+// distance returns the squared euclidean distance between two colours.
+func (c rgbf) distance(o rgbf) float64 {
+	r, g, b := c.r-o.r, c.g-o.g, c.b-o.b
+	return r*r + g*g + b*b
+}
+
+func rgbfFromRGB(c color.RGBA) rgbf {
+	return rgbf{float64(c.R), float64(c.G), float64(c.B)}
+}
+
+// screenPixels are the 256x192 pixels of a screen, before quantisation.
+type screenPixels [defaultHeight][defaultWidth]rgbf
+
+// newScreenPixels resamples the image to the ZX Spectrum screen size,
+// averaging the source pixels which map to each screen pixel. The first of
+// the border sizes matching the image dimensions is removed.
+func newScreenPixels(src image.Image, borders []borderSize) *screenPixels {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// remove the border when the image is a bordered screen at any scale
+	for _, border := range borders {
+		borderedWidth := defaultWidth + border.left + border.right
+		borderedHeight := defaultHeight + border.top + border.bottom
+
+		if width%borderedWidth == 0 && height%borderedHeight == 0 && width/borderedWidth == height/borderedHeight {
+			scale := width / borderedWidth
+			bounds = image.Rect(
+				bounds.Min.X+border.left*scale,
+				bounds.Min.Y+border.top*scale,
+				bounds.Max.X-border.right*scale,
+				bounds.Max.Y-border.bottom*scale,
+			)
+			width, height = bounds.Dx(), bounds.Dy()
+			break
+		}
+	}
+
+	screen := &screenPixels{}
+	for y := 0; y < defaultHeight; y++ {
+		y0 := bounds.Min.Y + y*height/defaultHeight
+		y1 := max(bounds.Min.Y+(y+1)*height/defaultHeight, y0+1)
+
+		for x := 0; x < defaultWidth; x++ {
+			x0 := bounds.Min.X + x*width/defaultWidth
+			x1 := max(bounds.Min.X+(x+1)*width/defaultWidth, x0+1)
+
+			var sum rgbf
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, _ := src.At(sx, sy).RGBA()
+					sum.r += float64(r >> 8)
+					sum.g += float64(g >> 8)
+					sum.b += float64(b >> 8)
+				}
+			}
+			count := float64((y1 - y0) * (x1 - x0))
+			screen[y][x] = rgbf{sum.r / count, sum.g / count, sum.b / count}
+		}
+	}
+
+	return screen
+}
+
+// cellColour is the INK (0-7), PAPER (0-7) and BRIGHT (0-1) values of a character cell.
+type cellColour struct {
+	ink, paper, bright uint8
+}
+
+// rgb returns the INK and PAPER colours of the cell from the palette.
+func (c cellColour) rgb(palette *Palette) (ink, paper rgbf) {
+	ink = rgbfFromRGB(palette[c.ink+c.bright*8])
+	paper = rgbfFromRGB(palette[c.paper+c.bright*8])
+	return ink, paper
+}
+
+// cellColours are the colours for all the character cells of a screen.
+type cellColours struct {
+	palette *Palette
+	cells   [defaultHeight / 8][screenWidthBytes]cellColour
+}
+
+// at returns the INK and PAPER colours of the cell containing the x/y pixel.
+func (c *cellColours) at(x, y int) (ink, paper rgbf) {
+	return c.cells[y/8][x/8].rgb(c.palette)
+}
+
+// inkPixels flags the screen pixels which are to be set to the INK colour.
+type inkPixels [defaultHeight][defaultWidth]bool
+
+// encodeCell stores the pixel and attribute bytes for the character cell
+// at the col/row position.
+func (s *scr) encodeCell(ink *inkPixels, colour cellColour, col, row int) {
+	var bitmap [8]uint8
+	inkCount := 0
+	for line := 0; line < 8; line++ {
+		for bit := 0; bit < 8; bit++ {
+			if colour.ink != colour.paper && ink[row*8+line][col*8+bit] {
+				bitmap[line] |= 0b10000000 >> bit
+				inkCount++
+			}
+		}
+	}
+
+	// the most used colour of the cell becomes the PAPER
+	if inkCount > 32 {
+		colour.ink, colour.paper = colour.paper, colour.ink
+		for line := range bitmap {
+			bitmap[line] = ^bitmap[line]
+		}
+	}
+
+	s.setCell(col, row, bitmap, attrFromColours(colour.ink, colour.paper, colour.bright))
+}
+
+// bestCellColour returns the colours that give the lowest error for the
+// pixels of a character cell. When mixed is set the error is calculated
+// against the nearest mix of the two colours, otherwise the nearest colour.
+func bestCellColour(screen *screenPixels, col, row int, mixed bool, palette *Palette) cellColour {
+	var best cellColour
+	bestError := -1.0
+
+	for b := uint8(0); b <= 1; b++ {
+		for i := uint8(0); i < 8; i++ {
+			for p := i; p < 8; p++ {
+				colour := cellColour{ink: i, paper: p, bright: b}
+				inkColour, paperColour := colour.rgb(palette)
+
+				var cellError float64
+				for line := 0; line < 8; line++ {
+					for bit := 0; bit < 8; bit++ {
+						px := screen[row*8+line][col*8+bit]
+						if mixed {
+							cellError += px.distance(mix(inkColour, paperColour, px))
+						} else {
+							cellError += min(px.distance(inkColour), px.distance(paperColour))
+						}
+					}
+				}
+
+				if bestError < 0 || cellError < bestError {
+					bestError = cellError
+					best = colour
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// inkAmount returns how far along the line from the paper to the ink
+// colour the pixel colour is, with 0 being the paper and 1 the ink.
+func inkAmount(ink, paper, px rgbf) float64 {
+	dr, dg, db := ink.r-paper.r, ink.g-paper.g, ink.b-paper.b
+	length := dr*dr + dg*dg + db*db
+	if length == 0 {
+		return 0
+	}
+	t := ((px.r-paper.r)*dr + (px.g-paper.g)*dg + (px.b-paper.b)*db) / length
+	return max(0, min(1, t))
+}
+
+// mix returns the colour between the ink and paper nearest to the pixel colour.
+func mix(ink, paper, px rgbf) rgbf {
+	t := inkAmount(ink, paper, px)
+	return rgbf{
+		paper.r + (ink.r-paper.r)*t,
+		paper.g + (ink.g-paper.g)*t,
+		paper.b + (ink.b-paper.b)*t,
+	}
+}
+
+// attrFromColours builds an attribute byte from the INK, PAPER and BRIGHT values.
+func attrFromColours(ink, paper, bright uint8) uint8 {
+	return bright<<6 | paper<<3 | ink
+}
@@ -0,0 +1,105 @@
+package image_test
+
+import (
+	"bytes"
+	goImage "image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// testSCR returns SCR data where every character cell has a different
+// ink/paper/bright combination, with fewer INK than PAPER pixels.
+func testSCR() []byte {
+	data := make([]byte, 6912)
+	for i := 0; i < 6144; i++ {
+		data[i] = 0b10010010 >> (i % 3)
+	}
+	for i := 0; i < 768; i++ {
+		ink := uint8(i % 8)
+		paper := (ink + 1 + uint8(i/8)%7) % 8
+		bright := uint8(i/64) % 2
+		data[6144+i] = bright<<6 | paper<<3 | ink
+	}
+	return data
+}
+
+func TestToSCR(t *testing.T) {
+	data := testSCR()
+
+	for _, o := range []options.Options{
+		{Scale: 1},
+		{Scale: 2, WithBorder: true},
+		{Scale: 3, WithBorder: true},
+		{Scale: 1, WithBorder: true, BorderSize: "full"},
+		{Scale: 2, WithBorder: true, BorderSize: "frame"},
+		{Scale: 2, WithBorder: true, BorderSize: "custom", BorderLeft: 8, BorderRight: 40, BorderTop: 4, BorderBottom: 20},
+	} {
+		img, err := image.FromSCR(bytes.NewReader(data), o)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		scr, err := image.ToSCR(img, o)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(scr) != 6912 {
+			t.Fatalf("expected 6912 bytes, got %d", len(scr))
+		}
+		if !bytes.Equal(scr[:6144], data[:6144]) {
+			t.Errorf("scale %d: pixel bytes do not match the original", o.Scale)
+		}
+		if !bytes.Equal(scr[6144:], data[6144:]) {
+			t.Errorf("scale %d: attribute bytes do not match the original", o.Scale)
+		}
+	}
+}
+
+func TestToSCR_Dither(t *testing.T) {
+	grey := goImage.NewUniform(color.RGBA{R: 0x77, G: 0x77, B: 0x77, A: 0xFF})
+	src := goImage.NewRGBA(goImage.Rect(0, 0, 256, 192))
+	draw.Draw(src, src.Bounds(), grey, goImage.Point{}, draw.Src)
+
+	countPixels := func(scr []byte) int {
+		count := 0
+		for _, b := range scr[:6144] {
+			count += bits.OnesCount8(b)
+		}
+		return count
+	}
+
+	t.Run("without dithering", func(t *testing.T) {
+		scr, err := image.ToSCR(src, options.Options{Dither: "none"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if count := countPixels(scr); count != 0 {
+			t.Errorf("expected no pixels to be set, got %d", count)
+		}
+	})
+
+	for _, method := range []string{"bayer2", "bayer4", "bayer8", "floyd-steinberg", "atkinson"} {
+		t.Run(method, func(t *testing.T) {
+			scr, err := image.ToSCR(src, options.Options{Dither: method})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			// a 50% grey should be a mix of black and white pixels
+			count := countPixels(scr)
+			if count < 256*192/4 || count > 256*192*3/4 {
+				t.Errorf("expected around half the pixels to be set, got %d", count)
+			}
+		})
+	}
+
+	t.Run("unknown method", func(t *testing.T) {
+		if _, err := image.ToSCR(src, options.Options{Dither: "random"}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
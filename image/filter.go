@@ -0,0 +1,251 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// The pixel-art upscaling filters (epx, scalex and xbr) are used to
+// reach the image scale factor instead of duplicating the pixels.
+
+// filterFactors returns the scale factors of each pass of the filter needed
+// for the full scale, along with the remaining factor which is scaled using
+// nearest-neighbour.
+//
+// EPX and xBR scale by 2, and the Scale2x/3x family also by 3, with the
+// largest factors being used first.
+func filterFactors(filter string, scale int) ([]int, int) {
+	var sizes []int
+	switch filter {
+	case "epx", "xbr":
+		sizes = []int{2}
+	case "scalex":
+		sizes = []int{3, 2}
+	}
+
+	var factors []int
+	for _, size := range sizes {
+		for scale%size == 0 {
+			factors = append(factors, size)
+			scale /= size
+		}
+	}
+	return factors, scale
+}
+
+// upscale scales the image by the scale factor using the named filter.
+func upscale(src *image.RGBA, scale int, filter string) *image.RGBA {
+	factors, remaining := filterFactors(filter, scale)
+
+	for _, factor := range factors {
+		switch {
+		case filter == "epx" || filter == "scalex" && factor == 2:
+			src = scale2x(src)
+		case filter == "scalex":
+			src = scale3x(src)
+		case filter == "xbr":
+			src = xbr2x(src)
+		}
+	}
+
+	return nearest(src, remaining)
+}
+
+// nearest scales the image by duplicating each pixel.
+func nearest(src *image.RGBA, scale int) *image.RGBA {
+	if scale == 1 {
+		return src
+	}
+
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			dst.SetRGBA(x, y, src.RGBAAt(x/scale, y/scale))
+		}
+	}
+	return dst
+}
+
+// toRGBA returns the image as an RGBA image with its origin at 0,0.
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+	return dst
+}
+
+// at returns the pixel at x/y, with coordinates outside the image being
+// clamped to the nearest edge pixel.
+func at(src *image.RGBA, x, y int) color.RGBA {
+	b := src.Bounds()
+	x = max(b.Min.X, min(x, b.Max.X-1))
+	y = max(b.Min.Y, min(y, b.Max.Y-1))
+	return src.RGBAAt(x, y)
+}
+
+// scale2x doubles the size of the image using the Scale2x (AdvMAME2x)
+// algorithm, which gives the same result as EPX.
+func scale2x(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*2, b.Dy()*2))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			//   A
+			// C P B
+			//   D
+			p := at(src, x, y)
+			a, bb, c, d := at(src, x, y-1), at(src, x+1, y), at(src, x-1, y), at(src, x, y+1)
+
+			e0, e1, e2, e3 := p, p, p, p
+			if c == a && c != d && a != bb {
+				e0 = a
+			}
+			if a == bb && a != c && bb != d {
+				e1 = bb
+			}
+			if d == c && d != bb && c != a {
+				e2 = c
+			}
+			if bb == d && bb != a && d != c {
+				e3 = d
+			}
+
+			dst.SetRGBA(x*2, y*2, e0)
+			dst.SetRGBA(x*2+1, y*2, e1)
+			dst.SetRGBA(x*2, y*2+1, e2)
+			dst.SetRGBA(x*2+1, y*2+1, e3)
+		}
+	}
+	return dst
+}
+
+// scale3x triples the size of the image using the Scale3x (AdvMAME3x) algorithm.
+func scale3x(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*3, b.Dy()*3))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			// A B C
+			// D E F
+			// G H I
+			a, bb, c := at(src, x-1, y-1), at(src, x, y-1), at(src, x+1, y-1)
+			d, e, f := at(src, x-1, y), at(src, x, y), at(src, x+1, y)
+			g, h, i := at(src, x-1, y+1), at(src, x, y+1), at(src, x+1, y+1)
+
+			out := [9]color.RGBA{e, e, e, e, e, e, e, e, e}
+			if bb != h && d != f {
+				if d == bb {
+					out[0] = d
+				}
+				if d == bb && e != c || bb == f && e != a {
+					out[1] = bb
+				}
+				if bb == f {
+					out[2] = f
+				}
+				if d == bb && e != g || d == h && e != a {
+					out[3] = d
+				}
+				if bb == f && e != i || h == f && e != c {
+					out[5] = f
+				}
+				if d == h {
+					out[6] = d
+				}
+				if d == h && e != i || h == f && e != g {
+					out[7] = h
+				}
+				if h == f {
+					out[8] = f
+				}
+			}
+
+			for n, col := range out {
+				dst.SetRGBA(x*3+n%3, y*3+n/3, col)
+			}
+		}
+	}
+	return dst
+}
+
+// xbr2x doubles the size of the image using the 2xBR algorithm, which
+// detects edges by comparing the weighted colour distances along the two
+// diagonals of each corner.
+func xbr2x(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*2, b.Dy()*2))
+
+	// the corners, as the direction from the centre pixel
+	corners := []image.Point{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			for _, corner := range corners {
+				// fetches a neighbouring pixel, with the offsets given for the
+				// bottom-right corner being rotated to the current corner
+				p := func(dx, dy int) color.RGBA {
+					if corner.X != corner.Y {
+						dx, dy = dy, dx
+					}
+					return at(src, x+dx*corner.X, y+dy*corner.Y)
+				}
+
+				//    A1 B1 C1
+				// A0 A  B  C  C4
+				// D0 D  E  F  F4
+				// G0 G  H  I  I4
+				//    G5 H5 I5
+				e, bb, c, d, f := p(0, 0), p(0, -1), p(1, -1), p(-1, 0), p(1, 0)
+				g, h, i := p(-1, 1), p(0, 1), p(1, 1)
+				f4, i4, h5, i5 := p(2, 0), p(2, 1), p(0, 2), p(1, 2)
+
+				col := e
+				wd1 := yuvDistance(e, c) + yuvDistance(e, g) + yuvDistance(i, f4) + yuvDistance(i, h5) + 4*yuvDistance(h, f)
+				wd2 := yuvDistance(h, d) + yuvDistance(h, i5) + yuvDistance(f, i4) + yuvDistance(f, bb) + 4*yuvDistance(e, i)
+				if wd1 < wd2 && e != f && e != h {
+					px := h
+					if yuvDistance(e, f) <= yuvDistance(e, h) {
+						px = f
+					}
+					col = blend(e, px, 0.5)
+				}
+
+				dst.SetRGBA(x*2+(corner.X+1)/2, y*2+(corner.Y+1)/2, col)
+			}
+		}
+	}
+	return dst
+}
+
+// yuv returns the YUV components of the colour.
+func yuv(c color.RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	y := 0.299*r + 0.587*g + 0.114*b
+	return y, 0.492 * (b - y), 0.877 * (r - y)
+}
+
+// yuvDistance returns the weighted YUV distance between the colours, as used by xBR.
+func yuvDistance(a, b color.RGBA) float64 {
+	y1, u1, v1 := yuv(a)
+	y2, u2, v2 := yuv(b)
+	return 48*abs(y1-y2) + 7*abs(u1-u2) + 6*abs(v1-v2)
+}
+
+// blend blends the two colours, with weight being the amount of b (0-1).
+func blend(a, b color.RGBA, weight float64) color.RGBA {
+	channel := func(x, y uint8) uint8 {
+		return uint8(float64(x)*(1-weight) + float64(y)*weight + 0.5)
+	}
+	return color.RGBA{R: channel(a.R, b.R), G: channel(a.G, b.G), B: channel(a.B, b.B), A: 0xFF}
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -9,39 +9,83 @@ import (
 
 // Dimension of a standard ZX Spectrum SCR image in pixels.
 const (
-	defaultWidth        = 256
-	defaultHeight       = 192
-	defaultWidthBorder  = 32 // border each side = 1/8th of the image width
-	defaultHeightBorder = 24 // border each side = 1/8th of the image height
+	defaultWidth  = 256
+	defaultHeight = 192
 )
 
+// defaultFlashDelay is the duration of a FLASH phase in 1/100s of a second,
+// the ULA swapping the INK and PAPER colours every 16 frames at 50Hz.
+const defaultFlashDelay = 32
+
 // Image is a ZX Spectrum compatible image implementation, which can be used
 // with the standard Go image.Image interface: At(), Bounds(), ColorModel().
+//
+// Only the unscaled screen pixels are stored, as an attribute and INK flag
+// for each pixel, with the scaling and border being applied when reading a
+// pixel. It also implements image.PalettedImage, so encoders can write the
+// image using its palette indexes directly.
 type Image struct {
-	enableFlashOutput bool       // when enabled will swap the ink/paper colours
-	hasFlashingPixels bool       // set when a pixel has the FLASH bit set
-	scale             int        // scale factor: 1-4
-	bordered          bool       // should the image include a border
-	borderColour      Colour     // if border enabled what colour? default: black
-	pixels            [][]Colour // the image pixels
+	enableFlashOutput bool            // when enabled will swap the ink/paper colours
+	hasFlashingPixels bool            // set when a pixel has the FLASH bit set
+	flashStatic       bool            // output a single FLASH phase instead of an animation
+	flashDelay        int             // duration of each FLASH phase in 1/100s of a second
+	flashLoopCount    int             // number of times the FLASH animation is played, 0 to loop forever
+	scale             int             // scale factor
+	filter            string          // upscaling filter used for the scale factor: none, epx, scalex, xbr
+	aspect            string          // pixel aspect ratio: square, or pal
+	fitWidth          int             // width to resize the output to fit, 0 for none
+	fitHeight         int             // height to resize the output to fit, 0 for none
+	crt               string          // CRT filter preset: scanlines, monitor, tv
+	width             int             // screen width in pixels: 256, or 512 in Timex hi-res mode
+	pixelHeight       int             // height of a screen pixel before scaling, 2 in hi-res mode to keep the 4:3 aspect
+	border            borderSize      // size of each side of the border, all zero when disabled
+	borderColour      Colour          // if border enabled what colour? default: black
+	borderLines       []uint8         // attribute of the border on each display line, overriding the border colour
+	attributes        []uint8         // the attribute for each screen pixel
+	ink               []bool          // set when a screen pixel uses the INK colour
+	ulaplus           *ULAplusPalette // the palette used by ULAplus screens
+	palette           *Palette        // the ZX Spectrum colours palette
+	info              []TextInfo      // descriptive text, such as a tape's title
+}
+
+// TextInfo is a key/value text string describing an image, such as its
+// title or publisher, which can be embedded in the output image file.
+type TextInfo struct {
+	Key   string
+	Value string
 }
 
 // New returns a new image with the given options.
 func New(opts options.Options) Image {
 	img := Image{
-		scale:    opts.Scale,
-		bordered: opts.WithBorder,
+		scale:       max(1, opts.Scale),
+		filter:      opts.Filter,
+		aspect:      opts.Aspect,
+		fitWidth:    opts.FitWidth,
+		fitHeight:   opts.FitHeight,
+		crt:         opts.CRT,
+		width:       defaultWidth,
+		pixelHeight: 1,
+		border:      borderSizeFor(opts),
+		palette:     paletteFor(opts.Palette),
+
+		enableFlashOutput: opts.FlashStatic && opts.FlashPhase == 1,
+		flashStatic:       opts.FlashStatic,
+		flashDelay:        opts.FlashDelay,
+		flashLoopCount:    opts.FlashLoopCount,
+	}
+	if img.flashDelay <= 0 {
+		img.flashDelay = defaultFlashDelay
+	}
+	if opts.ScreenMode == "hires" {
+		img.width = defaultWidth * 2
+		img.pixelHeight = 2
 	}
 	img.setBorderColour(opts.BorderColour)
+	img.setBorderLines(opts)
 
-	// initialize the pixels with the correct dimensions (with scaling and borders)
-	for row := 0; row < img.imageHeight(); row++ {
-		var columns []Colour
-		for col := 0; col < img.imageWidth(); col++ {
-			columns = append(columns, img.borderColour)
-		}
-		img.pixels = append(img.pixels, columns)
-	}
+	img.attributes = make([]uint8, img.width*defaultHeight)
+	img.ink = make([]bool, img.width*defaultHeight)
 
 	return img
 }
@@ -56,46 +100,139 @@ func (img *Image) HasFlashingPixels() bool {
 	return img.hasFlashingPixels
 }
 
-// Set the colour at the x/y coordinate, applying the borders and any scaling.
+// IsAnimated returns true when the image has FLASH attributes and is not
+// set to output a single static FLASH phase.
+func (img *Image) IsAnimated() bool {
+	return img.hasFlashingPixels && !img.flashStatic
+}
+
+// FlashDelay returns the duration of each FLASH phase in 1/100s of a second.
+func (img *Image) FlashDelay() int {
+	return img.flashDelay
+}
+
+// FlashLoopCount returns the number of times the FLASH animation is played,
+// with 0 meaning forever.
+func (img *Image) FlashLoopCount() int {
+	return img.flashLoopCount
+}
+
+// SetInfo sets the descriptive text of the image.
+func (img *Image) SetInfo(info []TextInfo) {
+	img.info = info
+}
+
+// Info returns the descriptive text of the image.
+func (img *Image) Info() []TextInfo {
+	return img.info
+}
+
+// IsULAplus returns true when the image uses a ULAplus palette.
+func (img *Image) IsULAplus() bool {
+	return img.ulaplus != nil
+}
+
+// Palette returns the colours used by the image: the 16 ZX Spectrum colours
+// of the selected palette, or the 64 colours of a ULAplus palette.
+func (img *Image) Palette() color.Palette {
+	if img.ulaplus == nil {
+		return img.palette.Colors()
+	}
+	return img.ulaplus.Colors()
+}
+
+// Set the colour of the screen pixel at the x/y coordinate. The borders and
+// any scaling are applied when the image is read.
 func (img *Image) Set(x, y int, c Colour) {
-	if x >= defaultWidth || y >= defaultHeight {
+	if x < 0 || y < 0 || x >= img.width || y >= defaultHeight {
 		return
 	}
 
-	// apply scaling to the starting point
-	y *= img.scale
-	x *= img.scale
-
-	// add padding for the left/top borders
-	y += img.scaledHeightBorder()
-	x += img.scaledWidthBorder()
+	img.attributes[y*img.width+x] = c.ATTR
+	img.ink[y*img.width+x] = c.IsPixel
+}
 
-	// generate the correct number of pixels for the scaling factor
-	for row := 0; row < img.scale; row++ {
-		for col := 0; col < img.scale; col++ {
-			img.pixels[y+row][x+col] = c
+// setByte sets the 8 pixels of a screen byte at the x (0-31) byte column and
+// y pixel row, using the colours of the attribute.
+func (img *Image) setByte(x, y int, pixel, attr uint8) {
+	// if one attribute has the FLASH bit set, which on ULAplus
+	// screens is used for selecting the palette group
+	if !img.hasFlashingPixels && img.ulaplus == nil {
+		if attr&0b10000000 != 0 {
+			img.hasFlashingPixels = true
 		}
 	}
+
+	// iterate over each pixel bit, add to image, setting its
+	// colour based on the corresponding attribute byte.
+	for bit := 0; bit < 8; bit++ {
+		// is pixel enabled for current bit?
+		isPixel := ((pixel << bit) & 0b10000000) > 0
+
+		xPos := x*8 + bit // convert byte position to correct pixel position
+		img.Set(xPos, y, Colour{ATTR: attr, IsPixel: isPixel})
+	}
 }
 
 // At returns the color of the pixel at the x/y coordinate.
 func (img *Image) At(x, y int) color.Color {
-	if x < img.imageWidth() && y < img.imageHeight() {
-		col := img.pixels[y][x]
-
-		// turns on the flash state if the FLASH bit was set in the SCR attribute,
-		// otherwise make sure it's turned off for all colours of this image
-		if img.enableFlashOutput {
-			col.UseFlashColour = true
-		} else {
-			col.UseFlashColour = false
-		}
-
-		return col
+	if x >= 0 && y >= 0 && x < img.imageWidth() && y < img.imageHeight() {
+		return img.colourAt(x, y)
 	}
 	return Colour{}
 }
 
+// ColorIndexAt returns the palette index of the pixel at the x/y coordinate.
+func (img *Image) ColorIndexAt(x, y int) uint8 {
+	if x >= 0 && y >= 0 && x < img.imageWidth() && y < img.imageHeight() {
+		return img.colourAt(x, y).index()
+	}
+	return 0
+}
+
+// colourAt returns the colour of the pixel at the x/y image coordinate,
+// mapping it to the border or the scaled screen pixel.
+func (img *Image) colourAt(x, y int) Colour {
+	col := img.borderColour
+	if len(img.borderLines) > 0 {
+		line := y / (img.scale * img.pixelHeight)
+		col = Colour{ATTR: img.borderLines[line%len(img.borderLines)]}
+	}
+	if img.ulaplus != nil {
+		// ULAplus always draws the border from the PAPER entries of CLUT group 0
+		col.ATTR &^= 0b01000000
+	}
+
+	border := img.scaledBorder()
+	x -= border.left
+	y -= border.top
+	if x >= 0 && y >= 0 && x < img.width*img.scale && y < defaultHeight*img.scale*img.pixelHeight {
+		i := y/(img.scale*img.pixelHeight)*img.width + x/img.scale
+		col = Colour{ATTR: img.attributes[i], IsPixel: img.ink[i]}
+	}
+
+	// turns on the flash state if the FLASH bit was set in the SCR attribute,
+	// otherwise it's turned off for all colours of this image
+	col.UseFlashColour = img.enableFlashOutput
+	col.ULAplus = img.ulaplus
+	col.Palette = img.palette
+
+	return col
+}
+
+// Paletted returns the image as an image.Paletted, using the current
+// FLASH output state.
+func (img *Image) Paletted() *image.Paletted {
+	paletted := image.NewPaletted(img.Bounds(), img.Palette())
+	for y := 0; y < paletted.Rect.Dy(); y++ {
+		row := paletted.Pix[y*paletted.Stride : y*paletted.Stride+paletted.Rect.Dx()]
+		for x := range row {
+			row[x] = img.colourAt(x, y).index()
+		}
+	}
+	return paletted
+}
+
 // Bounds returns the domain for which At can return non-zero color.
 func (img *Image) Bounds() image.Rectangle {
 	return image.Rectangle{
@@ -104,41 +241,52 @@ func (img *Image) Bounds() image.Rectangle {
 	}
 }
 
-// ColorModel returns the image's color model
+// ColorModel returns the image's color model, which is its palette.
 // U.S. English spelling used to match the image.Image interface
 func (img *Image) ColorModel() color.Model {
-	return color.RGBAModel
+	return img.Palette()
 }
 
 // imageWidth is the full width of the image, including the borders, with scaling applied.
 func (img *Image) imageWidth() int {
-	return defaultWidth*img.scale + img.scaledWidthBorder()*2
+	border := img.scaledBorder()
+	return img.width*img.scale + border.left + border.right
 }
 
 // imageHeight is the full height of the image, including the borders, with scaling applied.
 func (img *Image) imageHeight() int {
-	return defaultHeight*img.scale + img.scaledHeightBorder()*2
+	border := img.scaledBorder()
+	return defaultHeight*img.scale*img.pixelHeight + border.top + border.bottom
 }
 
-// scaledWidthBorder is border size, with scaling applied.
-func (img *Image) scaledWidthBorder() int {
-	if img.bordered {
-		return defaultWidthBorder * img.scale
-	}
-	return 0
+func (img *Image) setBorderColour(colour int) {
+	img.borderColour = Colour{ATTR: borderAttr(colour)}
 }
 
-// scaledHeightBorder is border size, with scaling applied.
-func (img *Image) scaledHeightBorder() int {
-	if img.bordered {
-		return defaultHeightBorder * img.scale
+// setBorderLines sets the border colour of each display line, from either
+// the given colours, or the generated tape loading stripes.
+func (img *Image) setBorderLines(opts options.Options) {
+	var colours []int
+	if len(opts.BorderLines) > 0 {
+		colours = opts.BorderLines
+	} else if opts.BorderStripes == "pilot" || opts.BorderStripes == "data" {
+		stripes := newTapeStripes(opts.BorderStripes, opts.StripeRandomness, opts.StripeSeed)
+		for _, colour := range stripes.lines(img.displayLines()) {
+			colours = append(colours, int(colour))
+		}
+	}
+
+	img.borderLines = nil
+	for _, colour := range colours {
+		img.borderLines = append(img.borderLines, borderAttr(colour))
 	}
-	return 0
 }
 
-func (img *Image) setBorderColour(colour int) {
+// borderAttr returns the attribute of a border colour (0-15), as a PAPER
+// colour matching the ULAplus palette entries, with 8-15 being BRIGHT.
+func borderAttr(colour int) uint8 {
 	if colour <= 0x00 || colour > 0x0F {
-		return
+		return 0
 	}
 
 	var attr = uint8(colour)
@@ -149,5 +297,5 @@ func (img *Image) setBorderColour(colour int) {
 		attr |= 1 << 6
 	}
 
-	img.borderColour = Colour{ATTR: attr, IsPixel: true}
+	return attr&0b01000000 | attr&0b00000111<<3
 }
@@ -66,10 +66,80 @@ func TestImage_Bounds(t *testing.T) {
 	}
 }
 
+func TestImage_BorderSize(t *testing.T) {
+	table := []struct {
+		name          string
+		opts          options.Options
+		width, height int
+	}{
+		{"normal", options.Options{Scale: 1, WithBorder: true}, 320, 240},
+		{"full", options.Options{Scale: 1, WithBorder: true, BorderSize: "full"}, 352, 296},
+		{"frame", options.Options{Scale: 2, WithBorder: true, BorderSize: "frame"}, 704, 624},
+		{"hi-res full", options.Options{Scale: 1, WithBorder: true, BorderSize: "full", ScreenMode: "hires"}, 704, 592},
+		{"disabled", options.Options{Scale: 1, BorderSize: "full"}, 256, 192},
+		{"custom", options.Options{Scale: 2, WithBorder: true, BorderSize: "custom", BorderLeft: 8, BorderRight: 16, BorderTop: 4, BorderBottom: 0}, 560, 392},
+	}
+	for _, tt := range table {
+		img := image.New(tt.opts)
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("%s: expected %dx%d, got %dx%d", tt.name, tt.width, tt.height, b.Dx(), b.Dy())
+		}
+	}
+
+	t.Run("screen position of custom sizes", func(t *testing.T) {
+		img := image.New(options.Options{Scale: 2, WithBorder: true, BorderColour: 2, BorderSize: "custom", BorderLeft: 8, BorderTop: 4})
+		img.Set(0, 0, image.Colour{ATTR: 0b00000001, IsPixel: true})
+
+		if r, _, b, _ := img.At(15, 7).RGBA(); r != 0xEEEE || b != 0 {
+			t.Errorf("expected a red border pixel, got: %04X, %04X", r, b)
+		}
+		if r, _, b, _ := img.At(16, 8).RGBA(); r != 0 || b != 0xEEEE {
+			t.Errorf("expected a blue screen pixel, got: %04X, %04X", r, b)
+		}
+	})
+}
+
 func TestImage_ColorModel(t *testing.T) {
 	img := image.New(opts)
 
-	if img.ColorModel() != color.RGBAModel {
-		t.Fatalf("unexcpeted colour model, got %s", img.ColorModel())
+	palette, ok := img.ColorModel().(color.Palette)
+	if !ok {
+		t.Fatalf("unexpected colour model, got %T", img.ColorModel())
+	}
+	if len(palette) != 16 {
+		t.Errorf("expected a 16 colour palette, got %d", len(palette))
+	}
+}
+
+func TestImage_ColorIndexAt(t *testing.T) {
+	img := image.New(options.Options{Scale: 2, WithBorder: true, BorderColour: 5})
+	img.Set(0, 0, image.Colour{ATTR: 0b11010011, IsPixel: true})
+
+	table := []struct {
+		name  string
+		x, y  int
+		flash bool
+		index uint8
+	}{
+		{"border", 0, 0, false, 5},
+		{"scaled pixel", 64, 48, false, 11},
+		{"scaled pixel", 65, 49, false, 11},
+		{"flashing pixel", 65, 49, true, 10},
+		{"next pixel", 66, 48, false, 0},
+	}
+	for _, p := range table {
+		img.SetFlashOutput(p.flash)
+		if index := img.ColorIndexAt(p.x, p.y); index != p.index {
+			t.Errorf("%s: expected index %d, got %d", p.name, p.index, index)
+		}
+	}
+
+	img.SetFlashOutput(false)
+	paletted := img.Paletted()
+	if paletted.Bounds() != img.Bounds() {
+		t.Errorf("unexpected paletted image bounds, got %v", paletted.Bounds())
+	}
+	if index := paletted.ColorIndexAt(64, 48); index != 11 {
+		t.Errorf("expected paletted index 11, got %d", index)
 	}
 }
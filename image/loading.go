@@ -0,0 +1,162 @@
+package image
+
+import (
+	"io"
+	"sort"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// Timings of the tape signal of a data block, in T-states, along with the
+// pulses of the pilot and data in stripes.go.
+const (
+	dataPilotPulses = 3223 // number of pilot pulses before a data block
+	syncPulse1      = 667
+	syncPulse2      = 735
+	tStatesPerCS    = 35000 // the number of T-states in 1/100s of a second at 3.5MHz
+	loadingEndDelay = 320   // the time the loaded screen is shown for, in 1/100s of a second
+	defaultFrameCS  = 4     // the default delay between animation frames, in 1/100s of a second
+)
+
+// Frame is a single image of an animation, with its delay in 1/100s of a second.
+type Frame struct {
+	Image *Image
+	Delay int
+}
+
+// LoadingAnimation reads the data from a SCR file, returning the frames of
+// an animation of the screen being loaded from tape: the pilot tone, then
+// the bytes appearing in the SCR memory order, with the border showing the
+// stripes of the tape signal. The loaded screen is then shown for a few
+// seconds. FLASH attributes flash at the FLASH delay over the whole
+// animation, unless a static FLASH phase is set.
+//
+// The LoadingSpeed option sets how many times faster than a real Spectrum
+// the screen loads, and FrameDelay the time between each frame.
+func LoadingAnimation(file io.Reader, opts options.Options) ([]Frame, error) {
+	s := &scr{}
+	if err := s.readFileBytes(file, opts.Lenient); err != nil {
+		return nil, err
+	}
+	screen := s.bytes()
+
+	speed := max(1, opts.LoadingSpeed)
+	delay := opts.FrameDelay
+	if delay <= 0 {
+		delay = defaultFrameCS
+	}
+
+	// the tape data block: flag byte, the screen, and the checksum
+	block := append([]byte{tapeDataFlag}, screen...)
+	var checksum byte
+	for _, b := range block {
+		checksum ^= b
+	}
+	signal := newTapeSignal(append(block, checksum))
+
+	var frames []Frame
+	for n := 0; ; n++ {
+		elapsed := n * delay // the time of the frame in the animation
+		t := int64(elapsed) * tStatesPerCS * int64(speed)
+
+		if t >= signal.end() {
+			loadedScreen := loadingScreen(screen, s.ulaplus, opts)
+			frames = append(frames, flashFrames(loadedScreen, elapsed, loadingEndDelay)...)
+			break
+		}
+
+		// the screen bytes loaded so far, skipping the flag byte
+		loaded := max(0, signal.bytesLoaded(t)-1)
+		loaded = min(loaded, len(screen))
+
+		img := loadingScreen(screen[:loaded], s.ulaplus, opts)
+		if img.IsAnimated() {
+			img.SetFlashOutput(elapsed/img.FlashDelay()%2 == 1)
+		}
+
+		img.borderLines = nil
+		for line := 0; line < img.displayLines(); line++ {
+			img.borderLines = append(img.borderLines, borderAttr(int(signal.colourAt(t+int64(line*tStatesPerLine)))))
+		}
+		frames = append(frames, Frame{Image: img, Delay: delay})
+	}
+
+	return frames, nil
+}
+
+// loadingScreen returns the image of a screen with only the loaded bytes
+// set, the rest of the screen being cleared to black INK on white PAPER.
+// The palette of a ULAplus screen is used for all of the loading screens.
+func loadingScreen(loaded []byte, ulaplus *ULAplusPalette, opts options.Options) *Image {
+	s := &scr{ulaplus: ulaplus}
+	for i := range s.attributes {
+		s.attributes[i] = bitmapOnlyAttribute
+	}
+	copy(s.pixels[:], loaded)
+	if len(loaded) > len(s.pixels) {
+		copy(s.attributes[:], loaded[len(s.pixels):])
+	}
+
+	return s.toImage(opts)
+}
+
+// tapeSignal is the sequence of pulses of a tape data block, with the border
+// colour the ROM loader shows during each pulse.
+type tapeSignal struct {
+	pulseEnds []int64 // the time each pulse ends, from the start of the signal
+	colours   []uint8 // the border colour during each pulse
+	byteEnds  []int64 // the time each byte has been loaded
+}
+
+// newTapeSignal returns the pilot tone, sync pulses, and the two pulses of
+// each bit of the data, most significant bit first.
+func newTapeSignal(data []byte) *tapeSignal {
+	s := &tapeSignal{}
+
+	var t int64
+	add := func(length int, colour uint8) {
+		t += int64(length)
+		s.pulseEnds = append(s.pulseEnds, t)
+		s.colours = append(s.colours, colour)
+	}
+
+	for i := 0; i < dataPilotPulses; i++ {
+		add(pilotPulse, []uint8{pilotColour1, pilotColour2}[i%2])
+	}
+	add(syncPulse1, pilotColour1)
+	add(syncPulse2, pilotColour2)
+
+	for _, b := range data {
+		for bit := 7; bit >= 0; bit-- {
+			length := zeroBitPulse
+			if b&(1<<bit) != 0 {
+				length = oneBitPulse
+			}
+			add(length, dataColour1)
+			add(length, dataColour2)
+		}
+		s.byteEnds = append(s.byteEnds, t)
+	}
+
+	return s
+}
+
+// end returns the time the signal ends.
+func (s *tapeSignal) end() int64 {
+	return s.pulseEnds[len(s.pulseEnds)-1]
+}
+
+// colourAt returns the border colour at the time, being black after the
+// signal has ended.
+func (s *tapeSignal) colourAt(t int64) uint8 {
+	i := sort.Search(len(s.pulseEnds), func(i int) bool { return s.pulseEnds[i] > t })
+	if i == len(s.pulseEnds) {
+		return 0
+	}
+	return s.colours[i]
+}
+
+// bytesLoaded returns the number of bytes fully loaded by the time.
+func (s *tapeSignal) bytesLoaded(t int64) int {
+	return sort.Search(len(s.byteEnds), func(i int) bool { return s.byteEnds[i] > t })
+}
@@ -0,0 +1,185 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestLoadingAnimation(t *testing.T) {
+	data := make([]byte, 6912)
+	for i := 0; i < 6144; i++ {
+		data[i] = 0xFF
+	}
+	for i := 6144; i < 6912; i++ {
+		data[i] = 0b00010110 // red PAPER, yellow INK
+	}
+
+	opts := options.Options{Scale: 1, WithBorder: true, LoadingSpeed: 16, FrameDelay: 5}
+	frames, err := image.LoadingAnimation(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(frames) < 10 {
+		t.Fatalf("expected the loading frames, got %d", len(frames))
+	}
+
+	isRGB := func(img *image.Image, x, y int, r, g, b uint32) bool {
+		cr, cg, cb, _ := img.At(x, y).RGBA()
+		return cr == r && cg == g && cb == b
+	}
+
+	t.Run("starts with a cleared screen and the pilot stripes", func(t *testing.T) {
+		first := frames[0].Image
+		if !isRGB(first, 32, 24, 0xEEEE, 0xEEEE, 0xEEEE) {
+			t.Errorf("expected a white screen")
+		}
+		if !isRGB(first, 0, 0, 0xEEEE, 0, 0) && !isRGB(first, 0, 0, 0, 0xEEEE, 0xEEEE) {
+			t.Errorf("expected a red or cyan border")
+		}
+		if frames[0].Delay != 5 {
+			t.Errorf("expected a delay of 5, got %d", frames[0].Delay)
+		}
+	})
+
+	t.Run("bytes appear in the SCR memory order", func(t *testing.T) {
+		// the first pixel row of each character row comes before the second
+		// pixel row of the first character row
+		loadedAt := func(x, y int) int {
+			for i, frame := range frames {
+				if isRGB(frame.Image, x+32, y+24, 0, 0, 0) {
+					return i
+				}
+			}
+			return -1
+		}
+		if row8, row1 := loadedAt(0, 8), loadedAt(0, 1); row8 < 0 || row8 >= row1 {
+			t.Errorf("expected row 8 to load before row 1, got frames %d and %d", row8, row1)
+		}
+	})
+
+	t.Run("ends with the loaded screen", func(t *testing.T) {
+		last := frames[len(frames)-1]
+		if !isRGB(last.Image, 32, 24, 0xEEEE, 0xEEEE, 0) {
+			t.Errorf("expected a yellow INK pixel")
+		}
+		if !isRGB(last.Image, 0, 0, 0, 0, 0) {
+			t.Errorf("expected a black border")
+		}
+		if last.Delay <= frames[0].Delay {
+			t.Errorf("expected the loaded screen to be shown for longer, got %d", last.Delay)
+		}
+	})
+	t.Run("loaded FLASH screen keeps the FLASH timing", func(t *testing.T) {
+		flashing := append([]byte{}, data...)
+		flashing[6144] |= 0b10000000
+
+		for _, flashDelay := range []int{400, 100, 7} {
+			opts := opts
+			opts.FlashDelay = flashDelay
+			frames, err := image.LoadingAnimation(bytes.NewReader(flashing), opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// skip the loading frames, which have a striped border, with the
+			// loaded screen frames being split where the FLASH phase changes
+			elapsed := 0
+			for len(frames) > 0 && !isRGB(frames[0].Image, 0, 0, 0, 0, 0) {
+				elapsed += frames[0].Delay
+				frames = frames[1:]
+			}
+			total := 0
+			for i, frame := range frames {
+				t0 := elapsed + total
+				end := min((t0/flashDelay+1)*flashDelay, elapsed+320)
+				if frame.Delay != end-t0 {
+					t.Errorf("FLASH delay %d: expected frame %d delay %d, got %d", flashDelay, i, end-t0, frame.Delay)
+				}
+				if !isRGB(frame.Image, 0, 0, 0, 0, 0) {
+					t.Errorf("FLASH delay %d: expected the loaded screen", flashDelay)
+				}
+				total += frame.Delay
+			}
+			if total != 320 {
+				t.Errorf("FLASH delay %d: expected the loaded screen to be shown for 320, got %d", flashDelay, total)
+			}
+		}
+	})
+
+	t.Run("FLASH delay and static FLASH while loading", func(t *testing.T) {
+		flashing := append([]byte{}, data...)
+		for i := 6144; i < 6912; i++ {
+			flashing[i] |= 0b10000000
+		}
+
+		// the INK pixels are yellow in the normal phase, red when swapped
+		phaseOf := func(img *image.Image) int {
+			switch {
+			case isRGB(img, 32, 24, 0xEEEE, 0xEEEE, 0):
+				return 0
+			case isRGB(img, 32, 24, 0xEEEE, 0, 0):
+				return 1
+			}
+			return -1
+		}
+
+		opts := opts
+		opts.FlashDelay = 10
+		frames, err := image.LoadingAnimation(bytes.NewReader(flashing), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		checked := 0
+		for i, frame := range frames {
+			if isRGB(frame.Image, 0, 0, 0, 0, 0) {
+				break // the loaded screen, with a black border
+			}
+			if phase := phaseOf(frame.Image); phase >= 0 {
+				if expected := i * opts.FrameDelay / opts.FlashDelay % 2; phase != expected {
+					t.Fatalf("frame %d: expected FLASH phase %d, got %d", i, expected, phase)
+				}
+				checked++
+			}
+		}
+		if checked < 4 {
+			t.Errorf("expected the attributes to be loaded in several frames, got %d", checked)
+		}
+
+		opts.FlashStatic, opts.FlashPhase = true, 1
+		frames, err = image.LoadingAnimation(bytes.NewReader(flashing), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		last := frames[len(frames)-1]
+		if last.Delay != 320 || phaseOf(last.Image) != 1 {
+			t.Errorf("expected a single static loaded screen, got delay %d", last.Delay)
+		}
+		for i, frame := range frames {
+			if phase := phaseOf(frame.Image); phase == 0 {
+				t.Fatalf("frame %d: expected the static FLASH phase", i)
+			}
+		}
+	})
+
+	t.Run("ULAplus palette", func(t *testing.T) {
+		ulaplus := append(append([]byte{}, data...), make([]byte, 64)...)
+		ulaplus[6912+6] = 0b00011100 // CLUT group 0 INK 6: GGGRRRBB red
+
+		frames, err := image.LoadingAnimation(bytes.NewReader(ulaplus), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for i, frame := range frames {
+			if isRGB(frame.Image, 32, 24, 0xEEEE, 0xEEEE, 0) {
+				t.Fatalf("frame %d: expected the ULAplus palette, got the Spectrum yellow", i)
+			}
+		}
+		if last := frames[len(frames)-1].Image; !isRGB(last, 32, 24, 0xFFFF, 0, 0) {
+			r, g, b, _ := last.At(32, 24).RGBA()
+			t.Errorf("expected the ULAplus red INK, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+}
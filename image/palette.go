@@ -0,0 +1,214 @@
+package image
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Palette is the RGB values of the ZX Spectrum colours: the normal colours
+// 0-7, followed by the bright colours 8-15.
+type Palette [16]color.RGBA
+
+// Colors returns the palette as a Go color.Palette.
+func (p *Palette) Colors() color.Palette {
+	var colours color.Palette
+	for _, c := range p {
+		colours = append(colours, c)
+	}
+	return colours
+}
+
+// newPalette returns a palette with the given normal and bright intensities.
+func newPalette(normal, bright uint8) *Palette {
+	var p Palette
+	for i := range p {
+		level := normal
+		if i > 7 {
+			level = bright
+		}
+
+		// the colour bits are: green, red, blue
+		var r, g, b uint8
+		if i&0b010 != 0 {
+			r = level
+		}
+		if i&0b100 != 0 {
+			g = level
+		}
+		if i&0b001 != 0 {
+			b = level
+		}
+		p[i] = color.RGBA{R: r, G: g, B: b, A: 0xFF}
+	}
+	return &p
+}
+
+var (
+	palettesMutex sync.RWMutex
+	palettes      = map[string]*Palette{
+		"default":       &sinclairColourMap,
+		"fuse":          newPalette(0xC0, 0xFF), // the Fuse emulator
+		"spectaculator": newPalette(0xCD, 0xFF), // the Spectaculator emulator
+		"zesarux":       newPalette(0xC0, 0xFF), // the ZEsarUX emulator, with the same levels as Fuse
+		"wikipedia":     newPalette(0xD7, 0xFF), // as given on the Wikipedia ZX Spectrum graphic modes page
+		"hardware":      &hardwareColourMap,
+	}
+)
+
+// hardwareColourMap is measured from the video output of a real Spectrum,
+// where the levels are not exactly equal, with green having a little blue.
+var hardwareColourMap = Palette{
+	// normal colours
+	{0x00, 0x00, 0x00, 0xFF},
+	{0x01, 0x00, 0xCE, 0xFF},
+	{0xCF, 0x01, 0x00, 0xFF},
+	{0xCF, 0x01, 0xCE, 0xFF},
+	{0x00, 0xCF, 0x15, 0xFF},
+	{0x01, 0xCF, 0xCF, 0xFF},
+	{0xCF, 0xCF, 0x15, 0xFF},
+	{0xCF, 0xCF, 0xCF, 0xFF},
+	// bright colours
+	{0x00, 0x00, 0x00, 0xFF},
+	{0x02, 0x00, 0xFD, 0xFF},
+	{0xFF, 0x02, 0x01, 0xFF},
+	{0xFF, 0x02, 0xFD, 0xFF},
+	{0x00, 0xFF, 0x1C, 0xFF},
+	{0x02, 0xFF, 0xFF, 0xFF},
+	{0xFF, 0xFF, 0x1D, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+}
+
+// RegisterPalette adds a named palette, which can then be selected with the
+// Palette option. Registering an existing name replaces that palette.
+func RegisterPalette(name string, p Palette) {
+	palettesMutex.Lock()
+	defer palettesMutex.Unlock()
+	palettes[name] = &p
+}
+
+// PaletteByName returns the built-in or registered palette with the name.
+func PaletteByName(name string) (Palette, bool) {
+	palettesMutex.RLock()
+	defer palettesMutex.RUnlock()
+	p, ok := palettes[name]
+	if !ok {
+		return Palette{}, false
+	}
+	return *p, true
+}
+
+// PaletteNames returns the names of all built-in and registered palettes.
+func PaletteNames() []string {
+	palettesMutex.RLock()
+	defer palettesMutex.RUnlock()
+
+	var names []string
+	for name := range palettes {
+		names = append(names, name)
+	}
+	return names
+}
+
+// paletteFor returns the named palette, with an empty or unknown name
+// returning the default palette.
+func paletteFor(name string) *Palette {
+	palettesMutex.RLock()
+	defer palettesMutex.RUnlock()
+
+	if p, ok := palettes[name]; ok {
+		return p
+	}
+	return &sinclairColourMap
+}
+
+// LoadPaletteFile reads a GIMP .gpl or a .json palette file.
+func LoadPaletteFile(filename string) (Palette, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return Palette{}, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gpl":
+		return ReadGPLPalette(file)
+	case ".json":
+		return ReadJSONPalette(file)
+	default:
+		return Palette{}, fmt.Errorf("unsupported palette file type: %s", filename)
+	}
+}
+
+// ReadGPLPalette reads a GIMP palette, which must contain 16 colours: the
+// normal colours 0-7 followed by the bright colours 8-15.
+func ReadGPLPalette(r io.Reader) (Palette, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Palette" {
+		return Palette{}, fmt.Errorf("palette error, missing GIMP Palette header")
+	}
+
+	var colours []color.RGBA
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.Contains(line, ":") {
+			continue // blank lines, comments, and the Name/Columns settings
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return Palette{}, fmt.Errorf("palette error, invalid colour: %s", line)
+		}
+		var rgb [3]uint8
+		for i := range rgb {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return Palette{}, fmt.Errorf("palette error, invalid colour: %s", line)
+			}
+			rgb[i] = uint8(v)
+		}
+		colours = append(colours, color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xFF})
+	}
+	if err := scanner.Err(); err != nil {
+		return Palette{}, err
+	}
+
+	return paletteFromColours(colours)
+}
+
+// ReadJSONPalette reads a JSON array of 16 "#RRGGBB" colour strings: the
+// normal colours 0-7 followed by the bright colours 8-15.
+func ReadJSONPalette(r io.Reader) (Palette, error) {
+	var values []string
+	if err := json.NewDecoder(r).Decode(&values); err != nil {
+		return Palette{}, fmt.Errorf("palette error: %w", err)
+	}
+
+	var colours []color.RGBA
+	for _, value := range values {
+		hex := strings.TrimPrefix(value, "#")
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return Palette{}, fmt.Errorf("palette error, invalid colour: %s", value)
+		}
+		colours = append(colours, color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF})
+	}
+
+	return paletteFromColours(colours)
+}
+
+func paletteFromColours(colours []color.RGBA) (Palette, error) {
+	var p Palette
+	if len(colours) != len(p) {
+		return p, fmt.Errorf("palette error, expected %d colours, got %d", len(p), len(colours))
+	}
+	copy(p[:], colours)
+	return p, nil
+}
@@ -0,0 +1,129 @@
+package image_test
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestReadGPLPalette(t *testing.T) {
+	gpl := `GIMP Palette
+Name: Test
+Columns: 8
+#
+  0   0   0	Black
+  0   0 192	Blue
+192   0   0	Red
+192   0 192	Magenta
+  0 192   0	Green
+  0 192 192	Cyan
+192 192   0	Yellow
+192 192 192	White
+  0   0   0	Bright Black
+  0   0 255	Bright Blue
+255   0   0	Bright Red
+255   0 255	Bright Magenta
+  0 255   0	Bright Green
+  0 255 255	Bright Cyan
+255 255   0	Bright Yellow
+255 255 255	Bright White
+`
+	palette, err := image.ReadGPLPalette(strings.NewReader(gpl))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fuse, _ := image.PaletteByName("fuse")
+	if palette != fuse {
+		t.Errorf("expected the palette to match the fuse palette")
+	}
+
+	t.Run("missing colours", func(t *testing.T) {
+		if _, err := image.ReadGPLPalette(strings.NewReader(gpl[:100])); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		if _, err := image.ReadGPLPalette(strings.NewReader(gpl[13:])); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestReadJSONPalette(t *testing.T) {
+	colours := []string{
+		`"#000000"`, `"#0000D7"`, `"#D70000"`, `"#D700D7"`, `"#00D700"`, `"#00D7D7"`, `"#D7D700"`, `"#D7D7D7"`,
+		`"#000000"`, `"#0000FF"`, `"#FF0000"`, `"#FF00FF"`, `"#00FF00"`, `"#00FFFF"`, `"#FFFF00"`, `"#FFFFFF"`,
+	}
+	palette, err := image.ReadJSONPalette(strings.NewReader("[" + strings.Join(colours, ",") + "]"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wikipedia, _ := image.PaletteByName("wikipedia")
+	if palette != wikipedia {
+		t.Errorf("expected the palette to match the wikipedia palette")
+	}
+
+	t.Run("invalid colour", func(t *testing.T) {
+		colours[3] = `"#D70"`
+		if _, err := image.ReadJSONPalette(strings.NewReader("[" + strings.Join(colours, ",") + "]")); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestImage_Palette(t *testing.T) {
+	image.RegisterPalette("test", image.Palette{2: color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xFF}})
+
+	img := image.New(options.Options{Scale: 1, Palette: "test"})
+	img.Set(0, 0, image.Colour{ATTR: 0b00000010, IsPixel: true})
+
+	if r, g, b, _ := img.At(0, 0).RGBA(); r != 0x1212 || g != 0x3434 || b != 0x5656 {
+		t.Errorf("expected the registered palette colour, got: %04X, %04X, %04X", r, g, b)
+	}
+	if len(img.Palette()) != 16 {
+		t.Errorf("expected 16 palette colours, got %d", len(img.Palette()))
+	}
+}
+
+func TestPaletteByName(t *testing.T) {
+	rgb := func(v uint32) color.RGBA {
+		return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+	}
+
+	tests := []struct {
+		name        string
+		blue        uint32 // colour 1
+		yellow      uint32 // colour 6
+		brightRed   uint32 // colour 10
+		brightGreen uint32 // colour 12
+	}{
+		{"default", 0x0000EE, 0xEEEE00, 0xFF0000, 0x00FF00},
+		{"fuse", 0x0000C0, 0xC0C000, 0xFF0000, 0x00FF00},
+		{"spectaculator", 0x0000CD, 0xCDCD00, 0xFF0000, 0x00FF00},
+		{"zesarux", 0x0000C0, 0xC0C000, 0xFF0000, 0x00FF00},
+		{"wikipedia", 0x0000D7, 0xD7D700, 0xFF0000, 0x00FF00},
+		{"hardware", 0x0100CE, 0xCFCF15, 0xFF0201, 0x00FF1C},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			palette, ok := image.PaletteByName(tc.name)
+			if !ok {
+				t.Fatalf("expected the %s palette", tc.name)
+			}
+			if palette[0] != rgb(0x000000) || palette[15] != rgb(0xFFFFFF) {
+				t.Errorf("expected black and bright white, got: %v, %v", palette[0], palette[15])
+			}
+			for i, expected := range map[int]uint32{1: tc.blue, 6: tc.yellow, 10: tc.brightRed, 12: tc.brightGreen} {
+				if palette[i] != rgb(expected) {
+					t.Errorf("expected colour %d to be %06X, got: %v", i, expected, palette[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// maxPaletteColours is the number of colours of a paletted image.
+const maxPaletteColours = 256
+
+// RenderPaletted returns the rendered image as a paletted image, such as for
+// a GIF. When the rendering adds no new colours, as with the EPX and Scale2x
+// filters, the image palette is used. Otherwise the palette is made from the
+// colours used, reduced to 256 colours with a median cut when there are more.
+func (img *Image) RenderPaletted() *image.Paletted {
+	rendered := img.Render()
+	if paletted, ok := rendered.(*image.Paletted); ok {
+		return paletted
+	}
+	src, ok := rendered.(*image.RGBA)
+	if !ok {
+		src = toRGBA(rendered)
+	}
+
+	counts := colourCounts(src)
+
+	colours := img.Palette()
+	if !paletteHas(colours, counts) {
+		colours = medianCut(counts, maxPaletteColours)
+	}
+
+	// map each colour only once to its nearest palette index
+	indexes := make(map[color.RGBA]uint8, len(counts))
+	for c := range counts {
+		indexes[c] = uint8(colours.Index(c))
+	}
+
+	dst := image.NewPaletted(src.Rect, colours)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			dst.Pix[y*dst.Stride+x] = indexes[src.RGBAAt(src.Rect.Min.X+x, src.Rect.Min.Y+y)]
+		}
+	}
+	return dst
+}
+
+// colourCounts returns the number of pixels of each colour of the image.
+func colourCounts(src *image.RGBA) map[color.RGBA]int {
+	counts := map[color.RGBA]int{}
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			counts[src.RGBAAt(x, y)]++
+		}
+	}
+	return counts
+}
+
+// paletteHas returns true when all the colours are in the palette.
+func paletteHas(palette color.Palette, counts map[color.RGBA]int) bool {
+	for c := range counts {
+		if palette.Convert(c) != color.Color(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// colourCount is a colour with the number of pixels using it.
+type colourCount struct {
+	colour color.RGBA
+	count  int
+}
+
+// medianCut returns a palette of at most n colours for the colour counts.
+// Starting with a box of all the colours, the box with the widest range of
+// a channel is repeatedly split at its median, weighted by the pixel counts,
+// with each box giving the average colour of its pixels. When there are no
+// more than n colours they are used as they are.
+func medianCut(counts map[color.RGBA]int, n int) color.Palette {
+	all := make([]colourCount, 0, len(counts))
+	for c, count := range counts {
+		all = append(all, colourCount{c, count})
+	}
+	// sort for a palette independent of the map order
+	sort.Slice(all, func(i, j int) bool { return rgbKey(all[i].colour) < rgbKey(all[j].colour) })
+
+	if len(all) <= n {
+		palette := make(color.Palette, 0, len(all))
+		for _, c := range all {
+			palette = append(palette, c.colour)
+		}
+		return palette
+	}
+
+	boxes := [][]colourCount{all}
+	for len(boxes) < n {
+		i, channel := widestBox(boxes)
+		if i < 0 {
+			break
+		}
+		box := boxes[i]
+		sort.SliceStable(box, func(a, b int) bool { return channelOf(box[a].colour, channel) < channelOf(box[b].colour, channel) })
+
+		total := 0
+		for _, c := range box {
+			total += c.count
+		}
+		split, sum := 1, 0
+		for j, c := range box[:len(box)-1] {
+			sum += c.count
+			if sum*2 >= total {
+				split = j + 1
+				break
+			}
+		}
+
+		boxes[i] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		palette = append(palette, averageColour(box))
+	}
+	return palette
+}
+
+// widestBox returns the index of the box of more than one colour with the
+// widest range of a channel, and that channel, or -1 when no box can be split.
+func widestBox(boxes [][]colourCount) (int, int) {
+	index, channel, widest := -1, 0, -1
+	for i, box := range boxes {
+		if len(box) < 2 {
+			continue
+		}
+		for ch := 0; ch < 3; ch++ {
+			lo, hi := 0xFF, 0
+			for _, c := range box {
+				v := channelOf(c.colour, ch)
+				lo, hi = min(lo, v), max(hi, v)
+			}
+			if hi-lo > widest {
+				index, channel, widest = i, ch, hi-lo
+			}
+		}
+	}
+	return index, channel
+}
+
+// averageColour returns the average colour of the box, weighted by the pixel counts.
+func averageColour(box []colourCount) color.RGBA {
+	var r, g, b, total int
+	for _, c := range box {
+		r += int(c.colour.R) * c.count
+		g += int(c.colour.G) * c.count
+		b += int(c.colour.B) * c.count
+		total += c.count
+	}
+	return color.RGBA{R: uint8(r / total), G: uint8(g / total), B: uint8(b / total), A: 0xFF}
+}
+
+// channelOf returns the red (0), green (1) or blue (2) channel of the colour.
+func channelOf(c color.RGBA, channel int) int {
+	switch channel {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	default:
+		return int(c.B)
+	}
+}
+
+// rgbKey returns the colour as a single value for sorting.
+func rgbKey(c color.RGBA) int {
+	return int(c.R)<<16 | int(c.G)<<8 | int(c.B)
+}
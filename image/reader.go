@@ -0,0 +1,46 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned when the screen data is not of an expected size.
+var (
+	ErrTruncated    = errors.New("screen data truncated")
+	ErrTrailingData = errors.New("screen data has trailing bytes")
+)
+
+// The sizes of the recognised screen files.
+const (
+	bitmapLength       = 6144     // pixels only, without attributes
+	ulaplusLength      = 6976     // SCR with a 64 byte ULAplus palette
+	timexLength        = 6144 * 2 // Timex hi-colour and hi-res screens
+	timexWithPortValue = timexLength + 1
+)
+
+// bitmapOnlyAttribute is used for screens without attributes: black INK on white PAPER.
+const bitmapOnlyAttribute = 0b00111000
+
+// readScreenData reads all the screen data, up to one byte more than the
+// largest recognised screen size, so that trailing data can be detected.
+func readScreenData(file io.Reader) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(file, timexWithPortValue+1))
+}
+
+// fitLength checks the data is the expected length, returning ErrTruncated
+// or ErrTrailingData when it is not. In lenient mode truncated data is
+// instead padded with zeros, and any trailing data is ignored.
+func fitLength(data []byte, length int, lenient bool) ([]byte, error) {
+	switch {
+	case len(data) < length && !lenient:
+		return nil, fmt.Errorf("%w: only %d of %d bytes read", ErrTruncated, len(data), length)
+	case len(data) > length && !lenient:
+		return nil, fmt.Errorf("%w: more than the expected %d bytes", ErrTrailingData, length)
+	case len(data) < length:
+		return append(data, make([]byte, length-len(data))...), nil
+	default:
+		return data[:length], nil
+	}
+}
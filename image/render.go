@@ -0,0 +1,126 @@
+package image
+
+import (
+	"image"
+	"math"
+)
+
+// palPixelAspect is the width of a ZX Spectrum pixel on a PAL display,
+// relative to its height. PAL square pixels are sampled at 14.75MHz over the
+// 576 interlaced lines, while the Spectrum's pixel clock is 7MHz, with each
+// of its lines being displayed over two of the interlaced lines.
+const palPixelAspect = 14.75 / 7.0 / 2
+
+// Render returns the image for output, with the upscaling filter, any aspect
+// ratio correction, resizing to fit the target dimensions, and the CRT filter
+// applied, using the current FLASH output state. Without any of these it is
+// the paletted image.
+func (img *Image) Render() image.Image {
+	if !img.filtered() && !img.aspectCorrected() && !img.resized() && !img.crtFiltered() {
+		return img.Paletted()
+	}
+
+	var out *image.RGBA
+	if img.filtered() {
+		// filter the screen from its unscaled pixels, including the border
+		unscaled := *img
+		unscaled.scale = 1
+		out = upscale(toRGBA(unscaled.Paletted()), img.scale, img.filter)
+	} else {
+		out = toRGBA(img.Paletted())
+	}
+
+	if img.aspectCorrected() {
+		// stretch the pixels horizontally to the PAL pixel aspect
+		width := int(math.Round(float64(out.Rect.Dx()) * palPixelAspect))
+		out = resize(out, width, out.Rect.Dy())
+	}
+
+	if img.resized() {
+		width, height := fitSize(out.Rect.Dx(), out.Rect.Dy(), img.fitWidth, img.fitHeight)
+		out = resize(out, width, height)
+	}
+
+	if img.crtFiltered() {
+		out = crt(out, img.displayColumns(), img.displayLines(), crtPresets[img.crt])
+	}
+
+	return out
+}
+
+// filtered returns true when an upscaling filter is to be applied.
+func (img *Image) filtered() bool {
+	return img.filter != "" && img.filter != "none"
+}
+
+// aspectCorrected returns true when the pixels are to be shown with the PAL
+// display aspect ratio, instead of being square.
+func (img *Image) aspectCorrected() bool {
+	return img.aspect == "pal"
+}
+
+// crtFiltered returns true when a CRT filter preset is to be applied.
+func (img *Image) crtFiltered() bool {
+	_, ok := crtPresets[img.crt]
+	return ok
+}
+
+// displayColumns returns the number of screen pixels across the image,
+// including the border, with hi-res pixels counted as half a pixel.
+func (img *Image) displayColumns() int {
+	return img.imageWidth() / (img.scale * img.width / defaultWidth)
+}
+
+// displayLines returns the number of scanlines displayed in the image,
+// including the border.
+func (img *Image) displayLines() int {
+	return img.imageHeight() / (img.scale * img.pixelHeight)
+}
+
+// resized returns true when the image is to be resized to fit the target dimensions.
+func (img *Image) resized() bool {
+	return img.fitWidth > 0 || img.fitHeight > 0
+}
+
+// fitSize returns the largest size of the image that fits the target
+// dimensions while keeping its aspect ratio. A zero target width or height
+// is derived from the other.
+func fitSize(width, height, targetWidth, targetHeight int) (int, int) {
+	ratio := math.Inf(1)
+	if targetWidth > 0 {
+		ratio = float64(targetWidth) / float64(width)
+	}
+	if targetHeight > 0 {
+		ratio = min(ratio, float64(targetHeight)/float64(height))
+	}
+
+	return max(1, int(math.Round(float64(width)*ratio))), max(1, int(math.Round(float64(height)*ratio)))
+}
+
+// resize scales the image to the given size using bilinear interpolation,
+// allowing non-integer scale factors.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	scaleX := float64(b.Dx()) / float64(width)
+	scaleY := float64(b.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		// the source position of the destination pixel centre
+		sy := (float64(y)+0.5)*scaleY - 0.5
+		y0 := int(math.Floor(sy))
+		fy := sy - float64(y0)
+
+		for x := 0; x < width; x++ {
+			sx := (float64(x)+0.5)*scaleX - 0.5
+			x0 := int(math.Floor(sx))
+			fx := sx - float64(x0)
+
+			top := blend(at(src, x0, y0), at(src, x0+1, y0), fx)
+			bottom := blend(at(src, x0, y0+1), at(src, x0+1, y0+1), fx)
+			dst.SetRGBA(x, y, blend(top, bottom, fy))
+		}
+	}
+	return dst
+}
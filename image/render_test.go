@@ -0,0 +1,143 @@
+package image_test
+
+import (
+	"bytes"
+	goImage "image"
+	"image/color"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// diagonalSCR returns a screen with a diagonal line of INK pixels in the first cell.
+func diagonalSCR() []byte {
+	data := make([]byte, 6912)
+	for y := 0; y < 8; y++ {
+		data[y*256] = 0b10000000 >> y
+	}
+	data[6144] = 0b00111000 // black ink, white paper
+	return data
+}
+
+func TestImage_Render(t *testing.T) {
+	table := []struct {
+		filter        string
+		scale         int
+		width, height int
+	}{
+		{"none", 1, 320, 240},
+		{"none", 6, 1920, 1440},
+		{"epx", 4, 1280, 960},
+		{"scalex", 3, 960, 720},
+		{"scalex", 6, 1920, 1440},
+		{"xbr", 4, 1280, 960},
+		{"xbr", 5, 1600, 1200},
+		{"xbr", 2, 640, 480},
+	}
+	for _, tt := range table {
+		opts := options.Options{Scale: tt.scale, Filter: tt.filter, WithBorder: true}
+		img, err := image.FromSCR(bytes.NewReader(diagonalSCR()), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		rendered := img.Render()
+		if b := rendered.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("%s x%d: expected %dx%d, got %dx%d", tt.filter, tt.scale, tt.width, tt.height, b.Dx(), b.Dy())
+		}
+	}
+
+	t.Run("without a filter it is paletted", func(t *testing.T) {
+		img, _ := image.FromSCR(bytes.NewReader(diagonalSCR()), options.Options{Scale: 2})
+		if _, ok := img.Render().(*goImage.Paletted); !ok {
+			t.Errorf("expected a paletted image")
+		}
+	})
+
+	t.Run("epx smooths diagonals", func(t *testing.T) {
+		img, _ := image.FromSCR(bytes.NewReader(diagonalSCR()), options.Options{Scale: 2, Filter: "epx"})
+		rendered := img.Render()
+
+		// the step between the diagonal pixels is filled in, where a
+		// nearest-neighbour scale would leave it white
+		black := color.RGBA{A: 0xFF}
+		if c := color.RGBAModel.Convert(rendered.At(2, 1)); c != black {
+			t.Errorf("expected pixel 2,1 to be black, got %v", c)
+		}
+		if c := color.RGBAModel.Convert(rendered.At(3, 0)); c == black {
+			t.Errorf("expected pixel 3,0 to be white")
+		}
+	})
+
+	t.Run("PAL aspect ratio", func(t *testing.T) {
+		table := []struct {
+			scale         int
+			filter        string
+			width, height int
+		}{
+			{1, "none", 337, 240},
+			{2, "none", 674, 480},
+			{2, "xbr", 674, 480},
+		}
+		for _, tt := range table {
+			opts := options.Options{Scale: tt.scale, Filter: tt.filter, WithBorder: true, Aspect: "pal"}
+			img, _ := image.FromSCR(bytes.NewReader(diagonalSCR()), opts)
+			if b := img.Render().Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("%s x%d: expected %dx%d, got %dx%d", tt.filter, tt.scale, tt.width, tt.height, b.Dx(), b.Dy())
+			}
+		}
+
+		opts := options.Options{Scale: 1, ScreenMode: "hires", WithBorder: true, Aspect: "pal"}
+		img, _ := image.FromHiRes(bytes.NewReader(make([]byte, 12288)), opts)
+		if b := img.Render().Bounds(); b.Dx() != 674 || b.Dy() != 480 {
+			t.Errorf("hi-res: expected 674x480, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("CRT filter", func(t *testing.T) {
+		luma := func(c color.Color) uint32 {
+			r, g, b, _ := c.RGBA()
+			return r + g + b
+		}
+
+		opts := options.Options{Scale: 4, WithBorder: true, BorderColour: 7, CRT: "scanlines"}
+		img, _ := image.FromSCR(bytes.NewReader(diagonalSCR()), opts)
+		rendered := img.Render()
+		if b := rendered.Bounds(); b.Dx() != 1280 || b.Dy() != 960 {
+			t.Errorf("expected 1280x960, got %dx%d", b.Dx(), b.Dy())
+		}
+		// each scanline is 4 pixels high, being darkest at its edges
+		if centre, edge := luma(rendered.At(10, 2)), luma(rendered.At(10, 0)); edge >= centre {
+			t.Errorf("expected a darker scanline gap, got %d, centre %d", edge, centre)
+		}
+
+		opts.CRT = "tv"
+		img, _ = image.FromSCR(bytes.NewReader(diagonalSCR()), opts)
+		rendered = img.Render()
+		if l := luma(rendered.At(0, 0)); l != 0 {
+			t.Errorf("expected the curved corner to be black, got %d", l)
+		}
+		if l := luma(rendered.At(640, 10)); l == 0 {
+			t.Errorf("expected the top border to be visible")
+		}
+	})
+
+	t.Run("fit to dimensions", func(t *testing.T) {
+		table := []struct {
+			fitWidth, fitHeight int
+			width, height       int
+		}{
+			{3840, 2160, 2880, 2160},
+			{1000, 0, 1000, 750},
+			{0, 100, 133, 100},
+		}
+		for _, tt := range table {
+			opts := options.Options{Scale: 2, WithBorder: true, FitWidth: tt.fitWidth, FitHeight: tt.fitHeight}
+			img, _ := image.FromSCR(bytes.NewReader(diagonalSCR()), opts)
+			if b := img.Render().Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("fit %dx%d: expected %dx%d, got %dx%d", tt.fitWidth, tt.fitHeight, tt.width, tt.height, b.Dx(), b.Dy())
+			}
+		}
+	})
+}
@@ -11,7 +11,7 @@ import (
 func FromSCR(file io.Reader, opts options.Options) (*Image, error) {
 	s := scr{}
 
-	if err := s.readFileBytes(file); err != nil {
+	if err := s.readFileBytes(file, opts.Lenient); err != nil {
 		return nil, err
 	}
 
@@ -19,7 +19,13 @@ func FromSCR(file io.Reader, opts options.Options) (*Image, error) {
 		opts.BorderColour = s.mostCommonColour()
 	}
 
+	return s.toImage(opts), nil
+}
+
+// toImage converts the raw SCR data to an Image.
+func (s *scr) toImage(opts options.Options) *Image {
 	img := New(opts)
+	img.ulaplus = s.ulaplus
 
 	// process the screen in 1/3 at a time (2048 bytes) for easier conversion
 	for i := 0; i < 3; i++ {
@@ -38,33 +44,19 @@ func FromSCR(file io.Reader, opts options.Options) (*Image, error) {
 				pixel := s.pixelsByteAt(x, y)
 				attr := s.attributeAt(x, y)
 
-				// if one attribute has the FLASH bit set
-				if !img.hasFlashingPixels {
-					if attr&0b10000000 != 0 {
-						img.hasFlashingPixels = true
-					}
-				}
-
-				// iterate over each pixel bit, add to image, setting its
-				// colour based on the corresponding attribute byte.
-				for bit := 0; bit < 8; bit++ {
-					// is pixel enabled for current bit?
-					isPixel := ((pixel << bit) & 0b10000000) > 0
-
-					xPos := x*8 + bit // convert byte position to correct pixel position
-					img.Set(xPos, yPos, Colour{ATTR: attr, IsPixel: isPixel})
-				}
+				img.setByte(x, yPos, pixel, attr)
 			}
 		}
 		s.verticalOffset += 64
 		s.attributesOffset += 256
 	}
 
-	return &img, nil
+	return &img
 }
 
 const (
-	screenWidthBytes = 32 // character tiles (bytes)
+	scrLength        = 6912 // pixels + attributes
+	screenWidthBytes = 32   // character tiles (bytes)
 	tilePosMod       = 64
 )
 
@@ -73,6 +65,7 @@ type scr struct {
 	// arrays for the raw SCR data
 	pixels     [6144]byte
 	attributes [768]byte
+	ulaplus    *ULAplusPalette // the optional palette of a ULAplus screen
 
 	// offset values used for building the correct image from the raw SCR bytes.
 	verticalOffset   int // increments in 1/3 screen height values (64 pixels)
@@ -105,30 +98,82 @@ func (s *scr) setPixelRowOffset(y int) {
 	}
 }
 
-func (s *scr) readFileBytes(file io.Reader) error {
-	n, err := file.Read(s.pixels[:])
+// pixelAddress returns the offset into the pixel memory for the byte at the
+// x (0-31) byte column and y (0-191) pixel row of the screen.
+func pixelAddress(x, y int) int {
+	return (y&0b11000000)<<5 | (y&0b00000111)<<8 | (y&0b00111000)<<2 | x
+}
+
+// setCell sets the eight pixel bytes and the attribute for the character
+// cell at the col (0-31) and row (0-23) position.
+func (s *scr) setCell(col, row int, bitmap [8]uint8, attr uint8) {
+	for line, b := range bitmap {
+		s.pixels[pixelAddress(col, row*8+line)] = b
+	}
+	s.attributes[row*screenWidthBytes+col] = attr
+}
+
+// bytes returns the SCR data in its file format: the pixels followed by the attributes.
+func (s *scr) bytes() []byte {
+	data := make([]byte, 0, len(s.pixels)+len(s.attributes))
+	data = append(data, s.pixels[:]...)
+	return append(data, s.attributes[:]...)
+}
+
+// readFileBytes reads the SCR data. Besides the standard 6912 bytes, a
+// 6144 byte bitmap-only screen (using black INK on white PAPER attributes)
+// and a 6976 byte ULAplus screen are accepted. In lenient mode any trailing
+// data is ignored, and a truncated screen has its missing pixels padded with
+// zeros, and its missing attributes set to the bitmap-only attribute, so the
+// bitmap is always shown.
+func (s *scr) readFileBytes(file io.Reader, lenient bool) error {
+	data, err := readScreenData(file)
 	if err != nil {
 		return err
-	} else if n != 6144 {
-		return fmt.Errorf("pixel error, only %d bytes read", n)
 	}
 
-	n, err = file.Read(s.attributes[:])
+	switch {
+	case len(data) == ulaplusLength:
+		// ULAplus screens have a 64 byte palette appended to the SCR data
+		palette := ULAplusPalette{}
+		copy(palette[:], data[scrLength:])
+		s.ulaplus = &palette
+		data = data[:scrLength]
+	case len(data) == bitmapLength || len(data) < scrLength && lenient:
+		if len(data) < bitmapLength {
+			data = append(data, make([]byte, bitmapLength-len(data))...)
+		}
+		for len(data) < scrLength {
+			data = append(data, bitmapOnlyAttribute)
+		}
+	case (len(data) == timexLength || len(data) == timexWithPortValue) && !lenient:
+		return fmt.Errorf("%w: %d bytes is a Timex screen, use the hicolour or hires screen mode", ErrTrailingData, len(data))
+	}
+
+	data, err = fitLength(data, scrLength, lenient)
 	if err != nil {
 		return err
-	} else if n != 768 {
-		return fmt.Errorf("attribute error, only %d bytes read", n)
 	}
 
+	copy(s.pixels[:], data[:bitmapLength])
+	copy(s.attributes[:], data[bitmapLength:])
+
 	return nil
 }
 
 // mostCommonColour returns a ZX Spectrum colour value (0-15) for the most
 // common ink/paper colour in the image.
 func (s *scr) mostCommonColour() int {
+	return mostCommonColour(s.attributes[:])
+}
+
+// mostCommonColour returns a ZX Spectrum colour value (0-15) for the most
+// common ink/paper colour of the attributes, with ties going to the lowest
+// colour value.
+func mostCommonColour(attributes []byte) int {
 	// calculate the colour counts in an image
-	var colourCount = map[byte]int{}
-	for _, attr := range s.attributes {
+	var colourCount [16]int
+	for _, attr := range attributes {
 		bright := attr&0b01000000 != 0
 
 		paper := (attr & 0b00111000) >> 3
@@ -145,7 +190,7 @@ func (s *scr) mostCommonColour() int {
 	}
 
 	// find the most common colour
-	var commonColourAttr byte
+	var commonColourAttr int
 	var commonColourCount int
 	for attr, count := range colourCount {
 		if count > commonColourCount {
@@ -154,5 +199,5 @@ func (s *scr) mostCommonColour() int {
 		}
 	}
 
-	return int(commonColourAttr)
+	return commonColourAttr
 }
@@ -0,0 +1,120 @@
+package image_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestFromSCR_ULAplus(t *testing.T) {
+	data := make([]byte, 6912+64)
+	data[0] = 0b10000000
+	data[6144] = 0b11000001      // FLASH+BRIGHT: palette group 3, ink 1
+	data[6912+48+1] = 0b00011100 // full red
+
+	img, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !img.IsULAplus() {
+		t.Errorf("expected a ULAplus image")
+	}
+	if img.HasFlashingPixels() {
+		t.Errorf("expected the FLASH bit to be used for the palette group")
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); r != 0xFFFF || g != 0 || b != 0 {
+		t.Errorf("expected a red pixel, got: %04X, %04X, %04X", r, g, b)
+	}
+
+	t.Run("bright border uses CLUT group 0", func(t *testing.T) {
+		border := append([]byte{}, data...)
+		border[6912+8+2] = 0b11100000    // group 0 PAPER 2: full green
+		border[6912+16+8+2] = 0b00000011 // group 1 PAPER 2: full blue
+
+		img, err := image.FromSCR(bytes.NewReader(border), options.Options{Scale: 1, WithBorder: true, BorderColour: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0xFFFF || b != 0 {
+			t.Errorf("expected a green border, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+
+	t.Run("without a palette", func(t *testing.T) {
+		img, err := image.FromSCR(bytes.NewReader(data[:6912]), options.Options{Scale: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if img.IsULAplus() {
+			t.Errorf("expected a standard image")
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0xFFFF {
+			t.Errorf("expected a bright blue pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+}
+
+func TestFromSCR_Sizes(t *testing.T) {
+	t.Run("bitmap only", func(t *testing.T) {
+		data := make([]byte, 6144)
+		data[0] = 0b10000000
+
+		img, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0 {
+			t.Errorf("expected a black INK pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+		if r, g, b, _ := img.At(1, 0).RGBA(); r != 0xEEEE || g != 0xEEEE || b != 0xEEEE {
+			t.Errorf("expected a white PAPER pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+
+	table := []struct {
+		name   string
+		length int
+		want   error
+	}{
+		{"truncated", 4000, image.ErrTruncated},
+		{"truncated attributes", 6500, image.ErrTruncated},
+		{"trailing data", 7000, image.ErrTrailingData},
+		{"timex screen", 12288, image.ErrTrailingData},
+		{"timex screen with port value", 12289, image.ErrTrailingData},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.length)
+			if _, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1}); !errors.Is(err, tt.want) {
+				t.Errorf("expected error '%s', got: %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		data := make([]byte, 6500)
+		data[6144] = 0b00000010 // red ink
+
+		for _, length := range []int{100, 7000, 12288} {
+			if _, err := image.FromSCR(bytes.NewReader(make([]byte, length)), options.Options{Scale: 1, Lenient: true}); err != nil {
+				t.Errorf("unexpected error for %d bytes: %s", length, err)
+			}
+		}
+
+		img, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1, Lenient: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0 {
+			t.Errorf("expected a black paper pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+		// the attributes after the 6500th byte are padded as bitmap-only
+		if r, g, b, _ := img.At(255, 191).RGBA(); r != 0xEEEE || g != 0xEEEE || b != 0xEEEE {
+			t.Errorf("expected a white padded attribute, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// SequenceAnimation converts the data of each SCR file, returning the frames
+// of an animation showing the screens in order, each for the FrameDelay. In
+// the standard screen mode a file of several concatenated 6912 byte screens
+// is split into its screens.
+//
+// The FLASH attributes keep flashing at the FLASH delay over the whole
+// animation, independent of the frame delay, so the frames of a screen with
+// FLASH attributes are split where its FLASH phase changes.
+func SequenceAnimation(files [][]byte, opts options.Options) ([]Frame, error) {
+	delay := opts.FrameDelay
+	if delay <= 0 {
+		delay = defaultFrameCS
+	}
+
+	var screens []*Image
+	for _, data := range files {
+		for _, screen := range splitScreens(data, opts.ScreenMode) {
+			img, err := sequenceScreen(screen, opts)
+			if err != nil {
+				return nil, fmt.Errorf("screen %d: %w", len(screens)+1, err)
+			}
+			screens = append(screens, img)
+		}
+	}
+
+	var frames []Frame
+	elapsed := 0 // the time of the frame in the animation
+	for _, img := range screens {
+		frames = append(frames, flashFrames(img, elapsed, delay)...)
+		elapsed += delay
+	}
+	return frames, nil
+}
+
+// splitScreens returns the screens of a multi-screen file, which is only
+// split in the standard screen mode when its length is a multiple of the
+// SCR length, otherwise the data is returned as a single screen.
+func splitScreens(data []byte, mode string) [][]byte {
+	if mode == "hicolour" || mode == "hires" || len(data) <= scrLength || len(data)%scrLength != 0 {
+		return [][]byte{data}
+	}
+
+	var screens [][]byte
+	for i := 0; i < len(data); i += scrLength {
+		screens = append(screens, data[i:i+scrLength])
+	}
+	return screens
+}
+
+// sequenceScreen converts the screen data using the decoder for the screen mode.
+func sequenceScreen(data []byte, opts options.Options) (*Image, error) {
+	switch opts.ScreenMode {
+	case "hicolour":
+		return FromHiColour(bytes.NewReader(data), opts)
+	case "hires":
+		return FromHiRes(bytes.NewReader(data), opts)
+	default:
+		return FromSCR(bytes.NewReader(data), opts)
+	}
+}
+
+// flashFrames returns the frames of an image shown from the elapsed time for
+// the delay, split where its FLASH phase changes, with each frame having the
+// FLASH phase of its time in the animation.
+func flashFrames(img *Image, elapsed, delay int) []Frame {
+	if !img.IsAnimated() {
+		return []Frame{{Image: img, Delay: delay}}
+	}
+
+	var frames []Frame
+	for t := elapsed; t < elapsed+delay; {
+		// the end of the current FLASH phase, or of the frame
+		end := min((t/img.FlashDelay()+1)*img.FlashDelay(), elapsed+delay)
+
+		phase := *img
+		phase.SetFlashOutput(t/img.FlashDelay()%2 == 1)
+		frames = append(frames, Frame{Image: &phase, Delay: end - t})
+		t = end
+	}
+	return frames
+}
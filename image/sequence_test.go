@@ -0,0 +1,88 @@
+package image_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// sequenceSCR returns a screen with all the attributes set to the value.
+func sequenceSCR(attr byte) []byte {
+	data := make([]byte, 6912)
+	for i := 6144; i < len(data); i++ {
+		data[i] = attr
+	}
+	return data
+}
+
+func TestSequenceAnimation(t *testing.T) {
+	opts := options.Options{Scale: 1, FrameDelay: 50}
+
+	t.Run("splits a multi-screen file", func(t *testing.T) {
+		multi := append(sequenceSCR(0b00001000), sequenceSCR(0b00010000)...)
+		files := [][]byte{multi, sequenceSCR(0b00011000)}
+
+		frames, err := image.SequenceAnimation(files, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(frames) != 3 {
+			t.Fatalf("expected 3 frames, got %d", len(frames))
+		}
+		for i, frame := range frames {
+			if frame.Delay != 50 {
+				t.Errorf("expected frame %d delay of 50, got %d", i, frame.Delay)
+			}
+			// the PAPER colours are blue, red, magenta
+			if r, _, _, _ := frame.Image.At(32, 24).RGBA(); (r != 0) != (i > 0) {
+				t.Errorf("expected frame %d to be screen %d", i, i+1)
+			}
+		}
+	})
+
+	t.Run("splits the frames at the FLASH phase changes", func(t *testing.T) {
+		// FLASH with blue PAPER and black INK
+		files := [][]byte{sequenceSCR(0), sequenceSCR(0b10001000)}
+
+		frames, err := image.SequenceAnimation(files, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// the second screen is shown from 50 to 100, with the FLASH phase changing at 64 and 96
+		expected := []struct {
+			delay int
+			blue  bool
+		}{{50, false}, {14, false}, {32, true}, {4, false}}
+		if len(frames) != len(expected) {
+			t.Fatalf("expected %d frames, got %d", len(expected), len(frames))
+		}
+		for i, e := range expected {
+			if frames[i].Delay != e.delay {
+				t.Errorf("expected frame %d delay of %d, got %d", i, e.delay, frames[i].Delay)
+			}
+			if i == 0 {
+				continue
+			}
+			// INK and PAPER are swapped in the FLASH phase
+			if _, _, b, _ := frames[i].Image.At(32, 24).RGBA(); (b != 0) != e.blue {
+				t.Errorf("expected frame %d blue to be %t", i, e.blue)
+			}
+		}
+	})
+
+	t.Run("reports the invalid screen", func(t *testing.T) {
+		files := [][]byte{sequenceSCR(0), make([]byte, 100)}
+
+		_, err := image.SequenceAnimation(files, opts)
+		if !errors.Is(err, image.ErrTruncated) {
+			t.Fatalf("expected a truncated error, got %v", err)
+		}
+		if !strings.HasPrefix(err.Error(), "screen 2:") {
+			t.Errorf("expected the screen number in the error, got %s", err)
+		}
+	})
+}
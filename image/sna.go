@@ -0,0 +1,91 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// The .SNA header holds the Z80 registers, followed by the border colour.
+// The RAM dump starting at address 0x4000 comes directly after the header,
+// so the first 6912 bytes after it are the screen.
+//
+// A 128K snapshot has the 48K dump of banks 5, 2 and the paged bank, then
+// the PC, the last write to port 0x7FFD, and a TR-DOS flag, followed by the
+// remaining banks in order. When the paged bank is 2 or 5 it is also stored
+// again with the remaining banks, making the longer 128K snapshot.
+const (
+	snaHeaderLength = 27
+	snaBorderOffset = 26
+
+	sna48KLength     = snaHeaderLength + 3*snaBankSize
+	sna128KLength    = sna48KLength + 4 + 5*snaBankSize
+	sna128KExtLength = sna128KLength + snaBankSize
+
+	snaBankSize         = 16384
+	snaPort7FFDOffset   = sna48KLength + 2
+	snaShadowScreenBank = 7
+)
+
+// FromSNA reads a 48K or 128K .SNA snapshot and converts its screen to an
+// Image, with the 128K shadow screen used when it was the active screen.
+// The border colour stored in the snapshot is used for the image border,
+// overriding the border colour options.
+func FromSNA(file io.Reader, opts options.Options) (*Image, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var screen []byte
+	switch len(data) {
+	case sna48KLength:
+		screen = data[snaHeaderLength:]
+	case sna128KLength, sna128KExtLength:
+		screen = sna128KScreen(data)
+	default:
+		return nil, fmt.Errorf("invalid SNA size of %d bytes, expected %d (48K), or %d or %d (128K)",
+			len(data), sna48KLength, sna128KLength, sna128KExtLength)
+	}
+
+	s := scr{}
+	if err := s.readFileBytes(bytes.NewReader(screen[:scrLength]), opts.Lenient); err != nil {
+		return nil, err
+	}
+
+	return s.toImage(withSnapshotBorder(opts, data[snaBorderOffset])), nil
+}
+
+// sna128KScreen returns the screen of a 128K snapshot, which is the bank 5
+// screen at the start of the RAM dump, unless port 0x7FFD has bit 3 set
+// selecting the bank 7 shadow screen.
+func sna128KScreen(data []byte) []byte {
+	port := data[snaPort7FFDOffset]
+	if port&0b00001000 == 0 {
+		return data[snaHeaderLength:]
+	}
+
+	paged := int(port & 0b00000111)
+	if paged == snaShadowScreenBank {
+		return data[snaHeaderLength+2*snaBankSize:]
+	}
+
+	// the remaining banks are stored in order, skipping 5, 2 and the paged bank
+	offset := sna48KLength + 4
+	for bank := 0; bank < snaShadowScreenBank; bank++ {
+		if bank != 5 && bank != 2 && bank != paged {
+			offset += snaBankSize
+		}
+	}
+	return data[offset:]
+}
+
+// withSnapshotBorder sets the border colour (0-7) stored in a snapshot as
+// the image border colour, replacing any border colour options.
+func withSnapshotBorder(opts options.Options, colour uint8) options.Options {
+	opts.BorderColour = int(colour & 0b00000111)
+	opts.AutoBorderColour = false
+	return opts
+}
@@ -0,0 +1,121 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestFromSNA(t *testing.T) {
+	screen := testSCR()
+
+	sna := make([]byte, 27, 49179)
+	sna[26] = 0x02 // red border
+	sna = append(sna, screen...)
+	sna = sna[:cap(sna)]
+
+	opts := options.Options{Scale: 1, WithBorder: true, AutoBorderColour: true}
+	img, err := image.FromSNA(bytes.NewReader(sna), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+	if r != 0xEEEE || g != 0 || b != 0 {
+		t.Errorf("expected a red border, got: %04X, %04X, %04X", r, g, b)
+	}
+
+	scr, err := image.FromSCR(bytes.NewReader(screen), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for y := 24; y < 24+192; y++ {
+		for x := 32; x < 32+256; x++ {
+			if img.At(x, y) != scr.At(x, y) {
+				t.Fatalf("screen pixel mismatch at %d,%d", x, y)
+			}
+		}
+	}
+
+	t.Run("truncated header", func(t *testing.T) {
+		if _, err := image.FromSNA(bytes.NewReader(sna[:20]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		if _, err := image.FromSNA(bytes.NewReader(sna[:len(sna)-1]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	// sna128K returns a 128K snapshot with the screen in bank 7, for the
+	// port 0x7FFD value, with the banks stored in the SNA order.
+	sna128K := func(port byte) []byte {
+		banks := make([][]byte, 8)
+		for i := range banks {
+			banks[i] = make([]byte, 16384)
+		}
+		copy(banks[7], screen)
+
+		paged := int(port & 0b111)
+		data := make([]byte, 27)
+		data[26] = 0x02 // red border
+		data = append(data, banks[5]...)
+		data = append(data, banks[2]...)
+		data = append(data, banks[paged]...)
+		data = append(data, 0x00, 0x80, port, 0x00)
+		for i, bank := range banks {
+			if i != 5 && i != 2 && i != paged {
+				data = append(data, bank...)
+			}
+		}
+		return data
+	}
+
+	for _, tc := range []struct {
+		name   string
+		port   byte
+		length int
+	}{
+		{"128K shadow screen", 0b00001000, 131103},
+		{"128K shadow screen paged in", 0b00001111, 131103},
+		{"128K shadow screen with bank 5 paged in", 0b00001101, 147487},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := sna128K(tc.port)
+			if len(data) != tc.length {
+				t.Fatalf("expected a %d byte snapshot, got %d", tc.length, len(data))
+			}
+			img, err := image.FromSNA(bytes.NewReader(data), opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for y := 24; y < 24+192; y++ {
+				for x := 32; x < 32+256; x++ {
+					if img.At(x, y) != scr.At(x, y) {
+						t.Fatalf("screen pixel mismatch at %d,%d", x, y)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("128K normal screen", func(t *testing.T) {
+		data := sna128K(0b00000000)
+		img, err := image.FromSNA(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		// bank 5 holds a blank screen
+		for y := 24; y < 24+192; y++ {
+			for x := 32; x < 32+256; x++ {
+				if img.At(x, y) != img.At(32, 24) {
+					t.Fatalf("expected the blank bank 5 screen, mismatch at %d,%d", x, y)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// Stats are the details of the contents of a SCR.
+type Stats struct {
+	SHA256           string        `json:"sha256"`             // hash of the SCR data
+	Attributes       map[uint8]int `json:"attributes"`         // number of character cells using each attribute
+	InkColours       [16]int       `json:"ink_colours"`        // number of cells using each INK colour, bright colours are 8-15
+	PaperColours     [16]int       `json:"paper_colours"`      // number of cells using each PAPER colour, bright colours are 8-15
+	FlashCells       int           `json:"flash_cells"`        // cells with the FLASH bit set
+	BrightCells      int           `json:"bright_cells"`       // cells with the BRIGHT bit set
+	EmptyCells       int           `json:"empty_cells"`        // cells with no INK pixels set
+	SolidCells       int           `json:"solid_cells"`        // cells with all INK pixels set
+	MostCommonColour int           `json:"most_common_colour"` // the colour used by the auto border option
+}
+
+// SCRStats reads a SCR and returns the details of its contents.
+func SCRStats(file io.Reader) (*Stats, error) {
+	s := scr{}
+	if err := s.readFileBytes(file, false); err != nil {
+		return nil, err
+	}
+
+	data := s.bytes()
+	if s.ulaplus != nil {
+		data = append(data, s.ulaplus[:]...)
+	}
+	hash := sha256.Sum256(data)
+
+	stats := &Stats{
+		SHA256:           hex.EncodeToString(hash[:]),
+		Attributes:       map[uint8]int{},
+		MostCommonColour: s.mostCommonColour(),
+	}
+
+	for row := 0; row < defaultHeight/8; row++ {
+		for col := 0; col < screenWidthBytes; col++ {
+			attr := s.attributes[row*screenWidthBytes+col]
+			stats.Attributes[attr]++
+
+			ink, paper, flash := Colour{ATTR: attr}.parseAttr()
+			stats.InkColours[ink]++
+			stats.PaperColours[paper]++
+			if flash {
+				stats.FlashCells++
+			}
+			if attr&0b01000000 != 0 {
+				stats.BrightCells++
+			}
+
+			empty, solid := true, true
+			for line := 0; line < 8; line++ {
+				pixels := s.pixels[pixelAddress(col, row*8+line)]
+				empty = empty && pixels == 0x00
+				solid = solid && pixels == 0xFF
+			}
+			if empty {
+				stats.EmptyCells++
+			}
+			if solid {
+				stats.SolidCells++
+			}
+		}
+	}
+
+	return stats, nil
+}
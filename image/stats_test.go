@@ -0,0 +1,54 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+)
+
+func TestSCRStats(t *testing.T) {
+	data := make([]byte, 6912)
+	for i := 0; i < 8; i++ {
+		data[i*256] = 0xFF // the first character cell is solid
+	}
+	data[1] = 0b00010000    // the second cell has one pixel
+	data[6144] = 0b11000010 // FLASH, BRIGHT, red ink, black paper
+	data[6145] = 0b01000010 // BRIGHT, red ink, black paper
+	for i := 6146; i < 6912; i++ {
+		data[i] = 0b00111000 // white paper, black ink
+	}
+
+	stats, err := image.SCRStats(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(stats.Attributes) != 3 || stats.Attributes[0b00111000] != 766 {
+		t.Errorf("unexpected attributes, got %v", stats.Attributes)
+	}
+	if stats.InkColours[10] != 2 || stats.InkColours[0] != 766 {
+		t.Errorf("unexpected ink colours, got %v", stats.InkColours)
+	}
+	if stats.PaperColours[8] != 2 || stats.PaperColours[7] != 766 {
+		t.Errorf("unexpected paper colours, got %v", stats.PaperColours)
+	}
+	if stats.FlashCells != 1 {
+		t.Errorf("expected 1 flash cell, got %d", stats.FlashCells)
+	}
+	if stats.BrightCells != 2 {
+		t.Errorf("expected 2 bright cells, got %d", stats.BrightCells)
+	}
+	if stats.EmptyCells != 766 {
+		t.Errorf("expected 766 empty cells, got %d", stats.EmptyCells)
+	}
+	if stats.SolidCells != 1 {
+		t.Errorf("expected 1 solid cell, got %d", stats.SolidCells)
+	}
+	if stats.MostCommonColour != 0 {
+		t.Errorf("unexpected most common colour, got %d", stats.MostCommonColour)
+	}
+	if len(stats.SHA256) != 64 {
+		t.Errorf("unexpected hash, got %s", stats.SHA256)
+	}
+}
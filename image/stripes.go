@@ -0,0 +1,135 @@
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// The timings of the tape loading signal, in T-states, as used by the ROM
+// loader which changes the border colour on each pulse.
+const (
+	tStatesPerLine = 224  // the duration of a display line on a 48K
+	pilotPulse     = 2168 // each half of the pilot tone
+	zeroBitPulse   = 855  // each half of a 0 bit
+	oneBitPulse    = 1710 // each half of a 1 bit
+)
+
+// The border colours of the tape loading stripes.
+const (
+	pilotColour1 = 2 // red
+	pilotColour2 = 5 // cyan
+	dataColour1  = 1 // blue
+	dataColour2  = 6 // yellow
+)
+
+// tapeStripes generates the border colour of each display line for the
+// pilot tone or data stripes shown while loading from tape. The stripes
+// continue from line to line, so can be used across animation frames.
+type tapeStripes struct {
+	pattern    string     // pilot or data
+	randomness float64    // variation of the length of each stripe: 0-1
+	rng        *rand.Rand // the source of the data bits and length variation
+
+	second    bool    // when the second colour of the pair is shown
+	remaining float64 // number of lines remaining of the current stripe
+	bitLength float64 // the length of the stripes of the current data bit
+}
+
+// newTapeStripes returns a new pilot or data stripe generator, with the
+// randomness varying the stripe lengths, and the seed selecting the data
+// bits and variation.
+func newTapeStripes(pattern string, randomness float64, seed int64) *tapeStripes {
+	s := &tapeStripes{pattern: pattern, randomness: randomness, rng: rand.New(rand.NewSource(seed))}
+	s.remaining = s.nextLength() * s.rng.Float64() // start part way through a stripe
+	return s
+}
+
+// line returns the border colour of the next display line.
+func (s *tapeStripes) line() uint8 {
+	colour := s.colour()
+
+	s.remaining--
+	for s.remaining <= 0 {
+		s.second = !s.second
+		s.remaining += s.nextLength()
+	}
+
+	return colour
+}
+
+// lines returns the border colours of the next number of display lines.
+func (s *tapeStripes) lines(count int) []uint8 {
+	lines := make([]uint8, count)
+	for i := range lines {
+		lines[i] = s.line()
+	}
+	return lines
+}
+
+// colour returns the colour of the current stripe.
+func (s *tapeStripes) colour() uint8 {
+	switch {
+	case s.pattern == "data" && s.second:
+		return dataColour2
+	case s.pattern == "data":
+		return dataColour1
+	case s.second:
+		return pilotColour2
+	default:
+		return pilotColour1
+	}
+}
+
+// nextLength returns the length in lines of the next stripe, with a data
+// bit being a pair of stripes of the same length.
+func (s *tapeStripes) nextLength() float64 {
+	pulse := float64(pilotPulse)
+	if s.pattern == "data" {
+		if !s.second || s.bitLength == 0 {
+			s.bitLength = zeroBitPulse
+			if s.rng.Intn(2) == 1 {
+				s.bitLength = oneBitPulse
+			}
+		}
+		pulse = s.bitLength
+	}
+
+	length := pulse / tStatesPerLine
+	return length * (1 + s.randomness*(s.rng.Float64()-0.5))
+}
+
+// ReadBorderLines reads a list of border colours (0-15) for each display
+// line, separated by spaces, commas, or new lines. Lines starting with a #
+// are comments.
+func ReadBorderLines(r io.Reader) ([]int, error) {
+	var colours []int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		for _, field := range fields {
+			colour, err := strconv.Atoi(field)
+			if err != nil || colour < 0 || colour > 15 {
+				return nil, fmt.Errorf("border lines error, invalid colour: %s", field)
+			}
+			colours = append(colours, colour)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(colours) == 0 {
+		return nil, fmt.Errorf("border lines error, no colours found")
+	}
+	return colours, nil
+}
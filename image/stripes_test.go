@@ -0,0 +1,72 @@
+package image_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestImage_BorderLines(t *testing.T) {
+	img := image.New(options.Options{Scale: 2, WithBorder: true, BorderColour: 7, BorderLines: []int{2, 13}})
+
+	table := []struct {
+		y       int
+		r, g, b uint32
+	}{
+		{0, 0xEEEE, 0x0000, 0x0000}, // line 0: red
+		{1, 0xEEEE, 0x0000, 0x0000},
+		{2, 0x0000, 0xFFFF, 0xFFFF}, // line 1: bright cyan
+		{4, 0xEEEE, 0x0000, 0x0000}, // repeated from line 2
+	}
+	for _, p := range table {
+		r, g, b, _ := img.At(0, p.y).RGBA()
+		if r != p.r || g != p.g || b != p.b {
+			t.Errorf("line at %d mismatch, got: %04X, %04X, %04X", p.y, r, g, b)
+		}
+	}
+}
+
+func TestImage_BorderStripes(t *testing.T) {
+	colours := func(pattern string, seed int64) map[uint32]int {
+		img := image.New(options.Options{Scale: 1, WithBorder: true, BorderStripes: pattern, StripeRandomness: 0.5, StripeSeed: seed})
+
+		found := map[uint32]int{}
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			r, g, b, _ := img.At(0, y).RGBA()
+			found[r>>8<<16|g>>8<<8|b>>8]++
+		}
+		return found
+	}
+
+	pilot := colours("pilot", 1)
+	if len(pilot) != 2 || pilot[0xEE0000] == 0 || pilot[0x00EEEE] == 0 {
+		t.Errorf("expected red and cyan pilot stripes, got %v", pilot)
+	}
+
+	data := colours("data", 1)
+	if len(data) != 2 || data[0x0000EE] == 0 || data[0xEEEE00] == 0 {
+		t.Errorf("expected blue and yellow data stripes, got %v", data)
+	}
+
+	if first, again := colours("data", 3), colours("data", 3); first[0x0000EE] != again[0x0000EE] {
+		t.Errorf("expected the same stripes from the same seed")
+	}
+}
+
+func TestReadBorderLines(t *testing.T) {
+	lines, err := image.ReadBorderLines(strings.NewReader("# stripes\n1, 2,3\n\n15 0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(lines) != 5 || lines[0] != 1 || lines[3] != 15 || lines[4] != 0 {
+		t.Errorf("unexpected lines, got %v", lines)
+	}
+
+	for _, data := range []string{"", "# none", "1 16", "red"} {
+		if _, err := image.ReadBorderLines(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error for '%s'", data)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// Tape block flag values, and the standard ROM header layout.
+const (
+	tapeHeaderFlag   = 0x00
+	tapeDataFlag     = 0xFF
+	tapeHeaderLength = 19 // flag + 17 header bytes + checksum
+	tapeTypeCode     = 3
+	screenAddress    = 16384
+)
+
+// TapeScreen is a loading screen found on a tape image.
+type TapeScreen struct {
+	Name  string // the (trimmed) 10 character filename of the header, empty when headerless
+	Image *Image
+}
+
+// tapeBlock is the data of a tape block, starting with the flag byte and
+// ending with the checksum byte.
+type tapeBlock []byte
+
+// valid reports whether the XOR of all the bytes, including the flag and
+// checksum, is zero.
+func (b tapeBlock) valid() bool {
+	if len(b) < 2 {
+		return false
+	}
+	var checksum byte
+	for _, v := range b {
+		checksum ^= v
+	}
+	return checksum == 0
+}
+
+// payload returns the block data without the flag and checksum bytes.
+func (b tapeBlock) payload() []byte {
+	return b[1 : len(b)-1]
+}
+
+// isHeader reports whether the block is a standard ROM header.
+func (b tapeBlock) isHeader() bool {
+	return len(b) == tapeHeaderLength && b[0] == tapeHeaderFlag
+}
+
+// isScreenData reports whether the block contains the data of a screen.
+func (b tapeBlock) isScreenData() bool {
+	return len(b) == scrLength+2 && b[0] == tapeDataFlag
+}
+
+// headerName returns the filename stored in a header block.
+func (b tapeBlock) headerName() string {
+	return strings.TrimRight(string(b[2:12]), " ")
+}
+
+// isScreenHeader reports whether the header is for a `CODE 16384,6912` block.
+func (b tapeBlock) isScreenHeader() bool {
+	length := int(b[12]) | int(b[13])<<8
+	start := int(b[14]) | int(b[15])<<8
+	return b[1] == tapeTypeCode && length == scrLength && start == screenAddress
+}
+
+// FromTAP reads a .TAP tape image and converts every screen found on it to
+// an Image. Screens are the data blocks of a `CODE 16384,6912` header, or
+// any headerless data block of 6912 bytes. Blocks with an invalid checksum
+// are ignored.
+func FromTAP(file io.Reader, opts options.Options) ([]TapeScreen, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []tapeBlock
+	for offset := 0; offset < len(data); {
+		if offset+2 > len(data) {
+			return nil, errors.New("TAP block error, missing block length")
+		}
+		length := int(data[offset]) | int(data[offset+1])<<8
+		offset += 2
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("TAP block %d error, data truncated", len(blocks)+1)
+		}
+		blocks = append(blocks, data[offset:offset+length])
+		offset += length
+	}
+
+	return tapeScreens(blocks, opts)
+}
+
+// tapeScreens returns the screens found in the tape blocks.
+func tapeScreens(blocks []tapeBlock, opts options.Options) ([]TapeScreen, error) {
+	var screens []TapeScreen
+	var header tapeBlock
+
+	for _, block := range blocks {
+		if !block.valid() {
+			header = nil
+			continue
+		}
+		if block.isHeader() {
+			header = block
+			continue
+		}
+
+		if block.isScreenData() {
+			var name string
+			if header != nil {
+				if !header.isScreenHeader() {
+					header = nil
+					continue // the data for some other file
+				}
+				name = header.headerName()
+			}
+
+			img, err := FromSCR(bytes.NewReader(block.payload()), opts)
+			if err != nil {
+				return nil, err
+			}
+			screens = append(screens, TapeScreen{Name: name, Image: img})
+		}
+		header = nil
+	}
+
+	if len(screens) == 0 {
+		return nil, errors.New("no screens found on the tape")
+	}
+	return screens, nil
+}
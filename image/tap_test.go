@@ -0,0 +1,74 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// tapBlock returns a TAP block: length, flag, data and checksum.
+func tapBlock(flag byte, data []byte) []byte {
+	checksum := flag
+	for _, b := range data {
+		checksum ^= b
+	}
+	length := len(data) + 2
+	block := []byte{byte(length), byte(length >> 8), flag}
+	block = append(block, data...)
+	return append(block, checksum)
+}
+
+// tapHeader returns a standard ROM header block.
+func tapHeader(blockType byte, name string, length, start int) []byte {
+	header := []byte{blockType}
+	header = append(header, []byte(name + "          ")[:10]...)
+	header = append(header, byte(length), byte(length>>8), byte(start), byte(start>>8), 0x00, 0x80)
+	return tapBlock(0x00, header)
+}
+
+func TestFromTAP(t *testing.T) {
+	screen := testSCR()
+
+	var tap []byte
+	tap = append(tap, tapHeader(0, "loader", 20, 10)...)
+	tap = append(tap, tapBlock(0xFF, make([]byte, 20))...)
+	tap = append(tap, tapHeader(3, "title", 6912, 16384)...)
+	tap = append(tap, tapBlock(0xFF, screen)...)
+	tap = append(tap, tapHeader(3, "code", 6912, 32768)...) // not loaded to the screen
+	tap = append(tap, tapBlock(0xFF, screen)...)
+	tap = append(tap, tapBlock(0xFF, screen)...) // headerless
+
+	bad := tapBlock(0xFF, screen)
+	bad[len(bad)-1] ^= 0xFF
+	tap = append(tap, bad...)
+
+	opts := options.Options{Scale: 1}
+	screens, err := image.FromTAP(bytes.NewReader(tap), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(screens) != 2 {
+		t.Fatalf("expected 2 screens, got %d", len(screens))
+	}
+	if screens[0].Name != "title" {
+		t.Errorf("expected screen name 'title', got '%s'", screens[0].Name)
+	}
+	if screens[1].Name != "" {
+		t.Errorf("expected headerless screen to have no name, got '%s'", screens[1].Name)
+	}
+
+	t.Run("truncated block", func(t *testing.T) {
+		if _, err := image.FromTAP(bytes.NewReader(tap[:100]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("no screens", func(t *testing.T) {
+		if _, err := image.FromTAP(bytes.NewReader(tap[:21+24]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package image
+
+import (
+	"io"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+// Size of a Timex screen bitmap, the hi-colour attribute map having the same size.
+const timexBitmapLength = 6144
+
+// FromHiColour converts a 12288 byte Timex/TC2048 hi-colour screen dump to
+// an Image. The bitmap (0x4000) is followed by the attribute map (0x6000),
+// which uses the same memory layout as the bitmap, giving each 8x1 pixel
+// strip its own attribute.
+func FromHiColour(file io.Reader, opts options.Options) (*Image, error) {
+	data, err := readTimexData(file, opts.Lenient)
+	if err != nil {
+		return nil, err
+	}
+	pixels, attributes := data[:timexBitmapLength], data[timexBitmapLength:timexLength]
+
+	if opts.AutoBorderColour {
+		opts.BorderColour = mostCommonColour(attributes)
+	}
+
+	img := New(opts)
+	for y := 0; y < defaultHeight; y++ {
+		for x := 0; x < screenWidthBytes; x++ {
+			address := pixelAddress(x, y)
+			img.setByte(x, y, pixels[address], attributes[address])
+		}
+	}
+
+	return &img, nil
+}
+
+// FromHiRes converts a Timex 512x192 hi-res screen dump to an Image. The
+// two bitmaps (0x4000 and 0x6000) supply the even and odd byte columns of
+// each pixel row. When the dump has a 12289th byte, this is the value of
+// port 0xFF, whose bits 3-5 give the INK colour, the PAPER being its
+// complement. The HiResInk option overrides this with the HiResColour.
+func FromHiRes(file io.Reader, opts options.Options) (*Image, error) {
+	bitmaps, err := readTimexData(file, opts.Lenient)
+	if err != nil {
+		return nil, err
+	}
+
+	var ink uint8
+	if len(bitmaps) == timexWithPortValue {
+		ink = (bitmaps[timexLength] & 0b00111000) >> 3
+	}
+	if opts.HiResInk {
+		ink = uint8(opts.HiResColour)
+	}
+	attr := attrFromColours(ink, 7-ink, 0)
+
+	img := New(opts)
+	for y := 0; y < defaultHeight; y++ {
+		for x := 0; x < screenWidthBytes; x++ {
+			address := pixelAddress(x, y)
+			img.setByte(x*2, y, bitmaps[address], attr)
+			img.setByte(x*2+1, y, bitmaps[timexBitmapLength+address], attr)
+		}
+	}
+
+	return &img, nil
+}
+
+// readTimexData reads a 12288 byte Timex screen, or 12289 bytes when the
+// port 0xFF value is included. Truncated data is padded with zeros in
+// lenient mode.
+func readTimexData(file io.Reader, lenient bool) ([]byte, error) {
+	data, err := readScreenData(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == timexWithPortValue {
+		return data, nil
+	}
+	return fitLength(data, timexLength, lenient)
+}
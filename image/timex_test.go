@@ -0,0 +1,105 @@
+package image_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestFromHiColour(t *testing.T) {
+	data := make([]byte, 12288)
+
+	// the first byte of the screen: lines 0 and 1 are in consecutive memory pages
+	data[0] = 0b11110000
+	data[256] = 0b11110000
+	data[6144] = 0b00000010     // line 0: red ink, black paper
+	data[6144+256] = 0b00100001 // line 1: blue ink, green paper
+
+	img, err := image.FromHiColour(bytes.NewReader(data), options.Options{Scale: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	table := []struct {
+		x, y    int
+		r, g, b uint32
+	}{
+		{0, 0, 0xEEEE, 0x0000, 0x0000},
+		{7, 0, 0x0000, 0x0000, 0x0000},
+		{0, 1, 0x0000, 0x0000, 0xEEEE},
+		{7, 1, 0x0000, 0xEEEE, 0x0000},
+	}
+	for _, p := range table {
+		r, g, b, _ := img.At(p.x, p.y).RGBA()
+		if r != p.r || g != p.g || b != p.b {
+			t.Errorf("pixel %d,%d mismatch, got: %04X, %04X, %04X", p.x, p.y, r, g, b)
+		}
+	}
+
+	t.Run("truncated attributes", func(t *testing.T) {
+		if _, err := image.FromHiColour(bytes.NewReader(data[:8000]), options.Options{Scale: 1}); !errors.Is(err, image.ErrTruncated) {
+			t.Errorf("expected a truncated error, got: %v", err)
+		}
+		if _, err := image.FromHiColour(bytes.NewReader(data[:8000]), options.Options{Scale: 1, Lenient: true}); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+}
+
+func TestFromHiRes(t *testing.T) {
+	data := make([]byte, 12289)
+	data[0] = 0b10000000    // first pixel of byte column 0
+	data[6144] = 0b10000000 // first pixel of byte column 1
+	data[12288] = 2 << 3    // port 0xFF: red ink, cyan paper
+
+	opts := options.Options{Scale: 1, WithBorder: true, ScreenMode: "hires"}
+	img, err := image.FromHiRes(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() != 640 || bounds.Dy() != 480 {
+		t.Errorf("expected a 640x480 image, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	table := []struct {
+		x, y    int
+		r, g, b uint32
+	}{
+		{64, 48, 0xEEEE, 0x0000, 0x0000}, // each pixel row is doubled
+		{64, 49, 0xEEEE, 0x0000, 0x0000},
+		{65, 48, 0x0000, 0xEEEE, 0xEEEE},
+		{64 + 8, 48, 0xEEEE, 0x0000, 0x0000},
+		{64 + 9, 48, 0x0000, 0xEEEE, 0xEEEE},
+	}
+	for _, p := range table {
+		r, g, b, _ := img.At(p.x, p.y).RGBA()
+		if r != p.r || g != p.g || b != p.b {
+			t.Errorf("pixel %d,%d mismatch, got: %04X, %04X, %04X", p.x, p.y, r, g, b)
+		}
+	}
+
+	t.Run("zero value options use the port value", func(t *testing.T) {
+		img, err := image.FromHiRes(bytes.NewReader(data), options.Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r != 0xEEEE || g != 0 || b != 0 {
+			t.Errorf("expected a red pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+
+	t.Run("hi-res colour option", func(t *testing.T) {
+		opts.HiResInk, opts.HiResColour = true, 1 // blue ink, yellow paper
+		img, err := image.FromHiRes(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if r, g, b, _ := img.At(64, 48).RGBA(); r != 0 || g != 0 || b != 0xEEEE {
+			t.Errorf("expected a blue pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+}
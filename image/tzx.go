@@ -0,0 +1,208 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+const tzxSignature = "ZXTape!\x1A"
+
+// TZX block IDs which contain standard tape block data, or the archive info.
+const (
+	tzxStandardSpeed = 0x10
+	tzxTurboSpeed    = 0x11
+	tzxPureData      = 0x14
+	tzxArchiveInfo   = 0x32
+)
+
+// The names of the TZX archive info text IDs.
+var tzxArchiveInfoNames = map[byte]string{
+	0x00: "Title",
+	0x01: "Publisher",
+	0x02: "Author",
+	0x03: "Year",
+	0x04: "Language",
+	0x05: "Type",
+	0x06: "Price",
+	0x07: "Protection",
+	0x08: "Origin",
+	0xFF: "Comment",
+}
+
+// tzxFixedLengths are the lengths of the blocks which have no data, or
+// whose data length is given by a count byte.
+var tzxFixedLengths = map[byte]int{
+	0x12: 4, // pure tone
+	0x20: 2, // pause
+	0x22: 0, // group end
+	0x23: 2, // jump to block
+	0x24: 2, // loop start
+	0x25: 0, // loop end
+	0x27: 0, // return from sequence
+	0x34: 8, // emulation info
+	0x5A: 9, // glue
+}
+
+// FromTZX reads a .TZX tape image and converts every screen found in the
+// standard speed, turbo speed and pure data blocks to an Image, as with
+// FromTAP. All other blocks are skipped, except for the archive info, which
+// is set as the text info of each image.
+func FromTZX(file io.Reader, opts options.Options) ([]TapeScreen, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 10 || string(data[:8]) != tzxSignature {
+		return nil, errors.New("TZX header error, invalid signature")
+	}
+
+	var blocks []tapeBlock
+	var info []TextInfo
+
+	r := tzxReader{data: data, offset: 10}
+	for r.offset < len(r.data) {
+		id := r.data[r.offset]
+		r.offset++
+
+		switch id {
+		case tzxStandardSpeed:
+			r.skip(2) // pause
+			blocks = append(blocks, r.block(r.number(2)))
+		case tzxTurboSpeed:
+			r.skip(15) // pulse timings, pilot length, used bits, pause
+			blocks = append(blocks, r.block(r.number(3)))
+		case tzxPureData:
+			r.skip(7) // pulse timings, used bits, pause
+			blocks = append(blocks, r.block(r.number(3)))
+		case tzxArchiveInfo:
+			info = tzxParseArchiveInfo(r.block(r.number(2)))
+		default:
+			r.skipBlock(id)
+		}
+
+		if r.err != nil {
+			return nil, fmt.Errorf("TZX block 0x%02X error: %w", id, r.err)
+		}
+	}
+
+	screens, err := tapeScreens(blocks, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, screen := range screens {
+		screen.Image.SetInfo(info)
+	}
+
+	return screens, nil
+}
+
+// tzxParseArchiveInfo returns the text strings of an archive info block.
+func tzxParseArchiveInfo(data []byte) []TextInfo {
+	var info []TextInfo
+	if len(data) == 0 {
+		return info
+	}
+
+	count := int(data[0])
+	offset := 1
+	for i := 0; i < count && offset+2 <= len(data); i++ {
+		id, length := data[offset], int(data[offset+1])
+		offset += 2
+		if offset+length > len(data) {
+			break
+		}
+
+		if name, ok := tzxArchiveInfoNames[id]; ok {
+			text := bytes.ReplaceAll(data[offset:offset+length], []byte{0x0D}, []byte{'\n'})
+			info = append(info, TextInfo{Key: name, Value: string(text)})
+		}
+		offset += length
+	}
+
+	return info
+}
+
+// tzxReader reads the values of TZX blocks, with the first error being
+// stored, after which all reads return zero values.
+type tzxReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// number reads a little-endian value of the given size in bytes.
+func (r *tzxReader) number(size int) int {
+	if r.err != nil {
+		return 0
+	}
+	if r.offset+size > len(r.data) {
+		r.err = errors.New("data truncated")
+		return 0
+	}
+
+	value := 0
+	for i := size - 1; i >= 0; i-- {
+		value = value<<8 | int(r.data[r.offset+i])
+	}
+	r.offset += size
+	return value
+}
+
+// block returns the next length bytes.
+func (r *tzxReader) block(length int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.offset+length > len(r.data) {
+		r.err = errors.New("data truncated")
+		return nil
+	}
+	data := r.data[r.offset : r.offset+length]
+	r.offset += length
+	return data
+}
+
+func (r *tzxReader) skip(length int) {
+	r.block(length)
+}
+
+// skipBlock skips over a block which does not contain any screen data.
+func (r *tzxReader) skipBlock(id byte) {
+	if length, ok := tzxFixedLengths[id]; ok {
+		r.skip(length)
+		return
+	}
+
+	switch id {
+	case 0x13: // pulse sequence
+		r.skip(r.number(1) * 2)
+	case 0x15: // direct recording
+		r.skip(5)
+		r.skip(r.number(3))
+	case 0x21, 0x30: // group start, text description
+		r.skip(r.number(1))
+	case 0x26: // call sequence
+		r.skip(r.number(2) * 2)
+	case 0x28: // select block
+		r.skip(r.number(2))
+	case 0x31: // message
+		r.skip(1)
+		r.skip(r.number(1))
+	case 0x33: // hardware type
+		r.skip(r.number(1) * 3)
+	case 0x35: // custom info: 10 byte ID string, then the length
+		r.skip(10)
+		r.skip(r.number(4))
+	case 0x40: // snapshot
+		r.skip(1)
+		r.skip(r.number(3))
+	default:
+		// CSW, generalized data, and all other (unknown) blocks
+		// store the length of the block in the first 4 bytes.
+		r.skip(r.number(4))
+	}
+}
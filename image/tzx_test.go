@@ -0,0 +1,97 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestFromTZX(t *testing.T) {
+	screen := testSCR()
+
+	tzx := []byte("ZXTape!\x1A\x01\x14")
+	tzx = append(tzx, 0x30, 4, 't', 'e', 'x', 't')  // text description
+	tzx = append(tzx, 0x21, 3, 'g', 'r', 'p', 0x22) // group start/end
+	tzx = append(tzx, 0x32, 26, 0, 3)               // archive info
+	tzx = append(tzx, 0x00, 8, 'S', 'c', 'r', 'e', 'e', 'n', '!', '!')
+	tzx = append(tzx, 0x01, 7, 'T', 'h', 'e', ' ', 'P', 'u', 'b')
+	tzx = append(tzx, 0x03, 4, '1', '9', '8', '4')
+	tzx = append(tzx, 0x20, 0xE8, 0x03) // pause
+
+	// standard speed header and screen: strip the TAP length bytes
+	header := tapHeader(3, "title", 6912, 16384)[2:]
+	tzx = append(tzx, 0x10, 0xE8, 0x03, byte(len(header)), 0)
+	tzx = append(tzx, header...)
+	data := tapBlock(0xFF, screen)[2:]
+	tzx = append(tzx, 0x10, 0xE8, 0x03, byte(len(data)), byte(len(data)>>8))
+	tzx = append(tzx, data...)
+
+	// turbo speed headerless screen
+	tzx = append(tzx, 0x11)
+	tzx = append(tzx, make([]byte, 15)...)
+	tzx = append(tzx, byte(len(data)), byte(len(data)>>8), 0)
+	tzx = append(tzx, data...)
+
+	// pure data headerless screen
+	tzx = append(tzx, 0x14)
+	tzx = append(tzx, make([]byte, 7)...)
+	tzx = append(tzx, byte(len(data)), byte(len(data)>>8), 0)
+	tzx = append(tzx, data...)
+
+	tzx = append(tzx, 0x5A, 'X', 'T', 'a', 'p', 'e', '!', 0x1A, 1, 20) // glue
+
+	opts := options.Options{Scale: 1}
+	screens, err := image.FromTZX(bytes.NewReader(tzx), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(screens) != 3 {
+		t.Fatalf("expected 3 screens, got %d", len(screens))
+	}
+	if screens[0].Name != "title" {
+		t.Errorf("expected screen name 'title', got '%s'", screens[0].Name)
+	}
+
+	info := screens[2].Image.Info()
+	expected := []image.TextInfo{{"Title", "Screen!!"}, {"Publisher", "The Pub"}, {"Year", "1984"}}
+	if len(info) != len(expected) {
+		t.Fatalf("expected %d info strings, got %d", len(expected), len(info))
+	}
+	for i := range expected {
+		if info[i] != expected[i] {
+			t.Errorf("unexpected info, got %v", info[i])
+		}
+	}
+
+	t.Run("invalid signature", func(t *testing.T) {
+		if _, err := image.FromTZX(bytes.NewReader(tzx[1:]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("custom info block", func(t *testing.T) {
+		custom := []byte("ZXTape!\x1A\x01\x14")
+		custom = append(custom, 0x35)
+		custom = append(custom, []byte("POKEs     ")...)
+		custom = append(custom, 5, 0, 0, 0, 'p', 'o', 'k', 'e', 's')
+		custom = append(custom, 0x10, 0xE8, 0x03, byte(len(data)), byte(len(data)>>8))
+		custom = append(custom, data...)
+
+		screens, err := image.FromTZX(bytes.NewReader(custom), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(screens) != 1 {
+			t.Errorf("expected 1 screen, got %d", len(screens))
+		}
+	})
+
+	t.Run("truncated block", func(t *testing.T) {
+		if _, err := image.FromTZX(bytes.NewReader(tzx[:len(tzx)-200]), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mrcook/scrconv/options"
+)
+
+const (
+	z80HeaderLength   = 30
+	z80FlagsOffset    = 12 // bits 1-3: border colour, bit 5: v1 data is compressed
+	z80PCOffset       = 6  // a zero PC indicates a v2/v3 snapshot
+	z80HardwareOffset = 34 // the hardware mode in the v2/v3 additional header
+	z80Port7FFDOffset = 35 // the last write to port 0x7FFD on 128K machines
+
+	z80ScreenPage       = 8  // memory page of RAM bank 5, at address 0x4000
+	z80ShadowScreenPage = 10 // memory page of RAM bank 7, the 128K shadow screen
+	z80PageSize         = 16384
+)
+
+// FromZ80 reads a .Z80 snapshot and converts its screen to an Image.
+// Version 1 snapshots, and version 2 and 3 paged snapshots are supported,
+// with the 128K shadow screen used when it was the active screen. As with
+// .SNA snapshots the stored border colour is used for the image border.
+func FromZ80(file io.Reader, opts options.Options) (*Image, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < z80HeaderLength {
+		return nil, errors.New("Z80 header error, file too short")
+	}
+
+	flags := data[z80FlagsOffset]
+	if flags == 0xFF {
+		flags = 1 // for compatibility, a value of 255 should be read as 1
+	}
+
+	var screen []byte
+	if data[z80PCOffset] == 0 && data[z80PCOffset+1] == 0 {
+		screen, err = z80PagedScreen(data)
+	} else {
+		screen, err = z80V1Screen(data[z80HeaderLength:], flags&0b00100000 != 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := scr{}
+	if err := s.readFileBytes(bytes.NewReader(screen), opts.Lenient); err != nil {
+		return nil, err
+	}
+
+	return s.toImage(withSnapshotBorder(opts, flags>>1)), nil
+}
+
+// z80V1Screen returns the screen from the 48K memory dump of a v1 snapshot.
+func z80V1Screen(data []byte, compressed bool) ([]byte, error) {
+	if !compressed {
+		if len(data) < scrLength {
+			return nil, fmt.Errorf("Z80 memory error, only %d bytes found", len(data))
+		}
+		return data[:scrLength], nil
+	}
+	return z80Decompress(data, scrLength)
+}
+
+// z80PagedScreen returns the screen from the memory pages of a v2/v3
+// snapshot, selecting the 128K shadow screen when port 0x7FFD has bit 3 set.
+func z80PagedScreen(data []byte) ([]byte, error) {
+	if len(data) < z80Port7FFDOffset+1 {
+		return nil, errors.New("Z80 header error, file too short")
+	}
+	extraLength := int(data[30]) | int(data[31])<<8
+	switch extraLength {
+	case 23, 54, 55: // v2, v3, and v3 with the port 0x1FFD byte
+	default:
+		return nil, fmt.Errorf("Z80 additional header error, unsupported length %d", extraLength)
+	}
+	offset := z80HeaderLength + 2 + extraLength
+	if len(data) < offset {
+		return nil, errors.New("Z80 additional header error, file too short")
+	}
+
+	page := z80ScreenPage
+	if z80Is128K(data[z80HardwareOffset], extraLength) && data[z80Port7FFDOffset]&0b00001000 != 0 {
+		page = z80ShadowScreenPage
+	}
+
+	// each memory block: length (2 bytes), page number (1 byte), data
+	for offset+3 <= len(data) {
+		length := int(data[offset]) | int(data[offset+1])<<8
+		number := int(data[offset+2])
+		offset += 3
+
+		compressed := true
+		if length == 0xFFFF {
+			length = z80PageSize
+			compressed = false
+		}
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("Z80 page %d error, data truncated", number)
+		}
+
+		if number == page {
+			block := data[offset : offset+length]
+			if !compressed {
+				return block[:scrLength], nil
+			}
+			return z80Decompress(block, scrLength)
+		}
+		offset += length
+	}
+
+	return nil, fmt.Errorf("Z80 screen error, memory page %d not found", page)
+}
+
+// z80Is128K reports whether the hardware mode is a 128K machine with the
+// shadow screen, the mode values differing between v2 (23 byte) and v3
+// additional headers. The Didaktik Kompakt (11) and the Timex machines (14,
+// 15 and 128) use the 48K memory pages, so are not 128K machines.
+func z80Is128K(mode uint8, extraLength int) bool {
+	if extraLength == 23 && mode < 7 {
+		return mode == 3 || mode == 4
+	}
+	switch mode {
+	case 4, 5, 6, 7, 8, 9, 10, 12, 13:
+		return true
+	}
+	return false
+}
+
+// z80Decompress expands the RLE compressed data, where the sequence
+// ED ED nn bb is the byte bb repeated nn times, until size bytes are output.
+func z80Decompress(data []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+
+	for i := 0; i < len(data) && len(out) < size; {
+		if i+3 < len(data) && data[i] == 0xED && data[i+1] == 0xED {
+			for n := 0; n < int(data[i+2]) && len(out) < size; n++ {
+				out = append(out, data[i+3])
+			}
+			i += 4
+			continue
+		}
+		out = append(out, data[i])
+		i++
+	}
+
+	if len(out) < size {
+		return nil, fmt.Errorf("Z80 decompression error, only %d bytes found", len(out))
+	}
+	return out, nil
+}
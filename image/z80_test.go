@@ -0,0 +1,130 @@
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// z80Compress RLE encodes runs of five or more bytes as: ED ED nn bb
+func z80Compress(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		run := 1
+		for i+run < len(data) && data[i+run] == data[i] && run < 255 {
+			run++
+		}
+		if run >= 5 {
+			out = append(out, 0xED, 0xED, byte(run), data[i])
+		} else {
+			out = append(out, data[i:i+run]...)
+		}
+		i += run
+	}
+	return out
+}
+
+func TestFromZ80(t *testing.T) {
+	screen := testSCR()
+	copy(screen[1000:2000], make([]byte, 1000)) // add some runs to compress
+
+	opts := options.Options{Scale: 1, WithBorder: true}
+	expected, err := image.FromSCR(bytes.NewReader(screen), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	assertImage := func(t *testing.T, img *image.Image) {
+		t.Helper()
+		r, g, b, _ := img.At(0, 0).RGBA()
+		if r != 0 || g != 0xEEEE || b != 0xEEEE {
+			t.Errorf("expected a cyan border, got: %04X, %04X, %04X", r, g, b)
+		}
+		for y := 24; y < 24+192; y++ {
+			for x := 32; x < 32+256; x++ {
+				if img.At(x, y) != expected.At(x, y) {
+					t.Fatalf("screen pixel mismatch at %d,%d", x, y)
+				}
+			}
+		}
+	}
+
+	t.Run("v1 compressed", func(t *testing.T) {
+		z80 := make([]byte, 30)
+		z80[6] = 0x00 // PC: 0x8000
+		z80[7] = 0x80
+		z80[12] = 5<<1 | 0b00100000 // cyan border, compressed
+		memory := append(screen, make([]byte, 49152-len(screen))...)
+		z80 = append(z80, z80Compress(memory)...)
+		z80 = append(z80, 0x00, 0xED, 0xED, 0x00)
+
+		img, err := image.FromZ80(bytes.NewReader(z80), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assertImage(t, img)
+	})
+
+	t.Run("v3 128K shadow screen", func(t *testing.T) {
+		z80 := make([]byte, 30+2+54)
+		z80[12] = 5 << 1 // cyan border
+		z80[30] = 54     // v3 additional header length
+		z80[34] = 4      // 128K
+		z80[35] = 0b00001000
+
+		// bank 5 (page 8) with a blank screen, bank 7 (page 10) uncompressed
+		bank5 := z80Compress(make([]byte, 16384))
+		z80 = append(z80, byte(len(bank5)), byte(len(bank5)>>8), 8)
+		z80 = append(z80, bank5...)
+		z80 = append(z80, 0xFF, 0xFF, 10)
+		z80 = append(z80, append(screen, make([]byte, 16384-len(screen))...)...)
+
+		img, err := image.FromZ80(bytes.NewReader(z80), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assertImage(t, img)
+	})
+
+	t.Run("v3 TS2068 ignores the shadow screen bit", func(t *testing.T) {
+		z80 := make([]byte, 30+2+54)
+		z80[12] = 5 << 1 // cyan border
+		z80[30] = 54     // v3 additional header length
+		z80[34] = 128    // TS2068
+		z80[35] = 0b00001000
+
+		z80 = append(z80, 0xFF, 0xFF, 8)
+		z80 = append(z80, append(screen, make([]byte, 16384-len(screen))...)...)
+
+		img, err := image.FromZ80(bytes.NewReader(z80), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assertImage(t, img)
+	})
+
+	t.Run("missing screen page", func(t *testing.T) {
+		z80 := make([]byte, 30+2+23)
+		z80[30] = 23
+		if _, err := image.FromZ80(bytes.NewReader(z80), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+	t.Run("truncated additional header", func(t *testing.T) {
+		z80 := make([]byte, 30+2+2)
+		z80[30] = 23
+		if _, err := image.FromZ80(bytes.NewReader(z80), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("unsupported additional header length", func(t *testing.T) {
+		z80 := make([]byte, 30+2+23)
+		z80[30] = 2
+		if _, err := image.FromZ80(bytes.NewReader(z80), opts); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
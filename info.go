@@ -0,0 +1,91 @@
+package scrconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+
+	"github.com/mrcook/scrconv/image"
+)
+
+// The PNG signature and IHDR chunk are always the first 33 bytes.
+const pngIHDREnd = 8 + 4 + 4 + 13 + 4
+
+// pngWithInfo inserts a tEXt chunk for each of the text info strings,
+// directly after the IHDR chunk of the encoded PNG.
+func pngWithInfo(data []byte, info []image.TextInfo) []byte {
+	var chunks []byte
+	for _, text := range info {
+		chunks = append(chunks, pngChunk("tEXt", []byte(text.Key+"\x00"+text.Value))...)
+	}
+	return insertBytes(data, pngIHDREnd, chunks)
+}
+
+// pngChunk returns a PNG chunk: length, type, data, and CRC.
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// gifWithInfo inserts a comment extension with the text info strings
+// after the logical screen descriptor and global colour table.
+func gifWithInfo(data []byte, info []image.TextInfo) ([]byte, error) {
+	const headerLength = 6 + 7 // header + logical screen descriptor
+	if len(data) < headerLength {
+		return nil, errors.New("invalid GIF data")
+	}
+
+	offset := headerLength
+	if flags := data[10]; flags&0x80 != 0 {
+		offset += 3 * (1 << ((flags & 0x07) + 1))
+	}
+
+	// the comment is stored in sub-blocks of up to 255 bytes
+	comment := []byte(infoText(info))
+	extension := []byte{0x21, 0xFE}
+	for len(comment) > 0 {
+		size := min(len(comment), 255)
+		extension = append(extension, byte(size))
+		extension = append(extension, comment[:size]...)
+		comment = comment[size:]
+	}
+	extension = append(extension, 0x00)
+
+	return insertBytes(data, offset, extension), nil
+}
+
+// jpgWithInfo inserts a COM segment with the text info strings directly
+// after the SOI marker of the encoded JPG.
+func jpgWithInfo(data []byte, info []image.TextInfo) []byte {
+	comment := []byte(infoText(info))
+	if len(comment) > 0xFFFF-2 {
+		comment = comment[:0xFFFF-2]
+	}
+
+	segment := []byte{0xFF, 0xFE}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(comment)+2))
+	segment = append(segment, comment...)
+
+	return insertBytes(data, 2, segment)
+}
+
+// infoText returns the text info as "Key: Value" lines.
+func infoText(info []image.TextInfo) string {
+	var lines []string
+	for _, text := range info {
+		lines = append(lines, text.Key+": "+text.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func insertBytes(data []byte, offset int, insert []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(data[:offset])
+	buf.Write(insert)
+	buf.Write(data[offset:])
+	return buf.Bytes()
+}
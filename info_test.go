@@ -0,0 +1,57 @@
+package scrconv_test
+
+import (
+	"bytes"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/mrcook/scrconv"
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func TestImageTextInfo(t *testing.T) {
+	img := image.New(options.Options{Scale: 1, WithBorder: true})
+	img.SetInfo([]image.TextInfo{{Key: "Title", Value: "Manic Miner"}, {Key: "Year", Value: "1983"}})
+
+	t.Run("PNG", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := scrconv.ImageToPNG(&buf, &img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("tEXtTitle\x00Manic Miner")) {
+			t.Errorf("expected a tEXt chunk with the title")
+		}
+		if _, err := png.Decode(&buf); err != nil {
+			t.Errorf("invalid PNG: %s", err)
+		}
+	})
+
+	t.Run("GIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := scrconv.ImageToGIF(&buf, &img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("Title: Manic Miner\nYear: 1983")) {
+			t.Errorf("expected a comment with the text info")
+		}
+		if _, err := gif.Decode(&buf); err != nil {
+			t.Errorf("invalid GIF: %s", err)
+		}
+	})
+
+	t.Run("JPG", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := scrconv.ImageToJPG(&buf, &img, 90); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("Title: Manic Miner")) {
+			t.Errorf("expected a comment with the text info")
+		}
+		if _, err := jpeg.Decode(&buf); err != nil {
+			t.Errorf("invalid JPG: %s", err)
+		}
+	})
+}
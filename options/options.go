@@ -2,26 +2,75 @@ package options
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 )
 
 type Options struct {
 	InFilename       string
+	ScreenMode       string // SCR screen mode: standard, hicolour, hires
+	Lenient          bool   // pad truncated screens with zeros and ignore trailing data
+	HiResInk         bool   // use the HiResColour instead of the screen's port 0xFF value
+	HiResColour      int    // hi-res INK colour 0-7 (PAPER is its complement)
+	ImgFilename      string // image to convert to a SCR, instead of a SCR to an image
+	OutFilename      string // output filename, "-" for stdout, default: derived from the input filename
+	OutputDir        string // directory for the output files, default: the input file directory
+	KeepExtension    bool   // keep the input filename extension in the output filename, for inputs sharing a basename
+	Recursive        bool   // include subdirectories when converting a directory
+	Workers          int    // number of files converted concurrently in a batch, 0 for the number of CPUs
 	ImageFormat      string
 	Scale            int
+	Filter           string // pixel-art upscaling filter: none, epx, scalex, xbr
+	Aspect           string // pixel aspect ratio: square, or pal for the pixel shape on a PAL TV
+	FitWidth         int    // resize the output to fit this width, keeping the aspect ratio, 0 for none
+	FitHeight        int    // resize the output to fit this height, keeping the aspect ratio, 0 for none
+	CRT              string // CRT emulation filter preset: none, scanlines, monitor, tv
 	WithBorder       bool
+	BorderSize       string // border geometry preset: normal, full, frame, or custom for the BorderLeft/Right/Top/Bottom sizes
+	BorderLeft       int    // custom left border size in pixels, at scale 1
+	BorderRight      int    // custom right border size in pixels, at scale 1
+	BorderTop        int    // custom top border size in pixels, at scale 1
+	BorderBottom     int    // custom bottom border size in pixels, at scale 1
 	BorderColour     int
+	BorderLines      []int   // border colour (0-15) of each display line from the top, repeated when fewer than the lines
+	BorderStripes    string  // generated tape loading border stripes: none, pilot, data
+	StripeRandomness float64 // variation of the length of each border stripe: 0-1
+	StripeSeed       int64   // seed for the data bits and variation of the border stripes
 	AutoBorderColour bool
+	Palette          string // name of a built-in or registered colour palette, empty for the default
+	Dither           string // dithering method when converting an image to a SCR
+	FlashDelay       int    // duration of each FLASH phase in 1/100s of a second, 0 for the default
+	FlashLoopCount   int    // number of times the FLASH animation is played, 0 to loop forever
+	FlashStatic      bool   // output a single FLASH phase instead of an animation
+	FlashPhase       int    // the FLASH phase of a static image: 0 (normal) or 1 (INK/PAPER swapped)
+	Loading          bool   // output an animation of the screen being loaded from tape
+	LoadingSpeed     int    // how many times faster than a real Spectrum the loading animation is, 0 for real time
+	Sequence         bool   // output the screens of the input files as one animation, in order
+	FrameDelay       int    // delay between the frames of an animation in 1/100s of a second, 0 for the default
+	LoopCount        int    // number of times a sequence animation is played, 0 to loop forever
 }
 
+// OutputFilename returns the output filename when given, otherwise it is
+// derived from the input filename, with stdin ("-") being output to stdout.
 func (o Options) OutputFilename() string {
-	path := filepath.Dir(o.InFilename)
-	ext := filepath.Ext(o.InFilename)
-	name := strings.TrimSuffix(filepath.Base(o.InFilename), ext)
+	if len(o.OutFilename) > 0 {
+		return o.OutFilename
+	}
+	if o.InFilename == "-" || o.ImgFilename == "-" {
+		return "-"
+	}
+
+	if len(o.ImgFilename) > 0 {
+		return filepath.Join(o.outputDir(o.ImgFilename), o.inputName(o.ImgFilename)+".scr")
+	}
+
+	path := o.outputDir(o.InFilename)
+	name := o.inputName(o.InFilename)
 
+	ext := filepath.Ext(o.InFilename)
 	if len(o.ImageFormat) > 0 {
-		ext = "." + o.ImageFormat
+		ext = o.formatExtension()
 	} else {
 		name += ".new"
 	}
@@ -29,34 +78,127 @@ func (o Options) OutputFilename() string {
 	return filepath.Join(path, name+ext)
 }
 
+// OutputFilenameFor returns the filename for a named image, such as a screen
+// found on a tape, in the same directory as the input file, prefixed with the
+// input file name. Any characters not safe for a filename are replaced with
+// an underscore.
+func (o Options) OutputFilenameFor(name string) string {
+	safeName := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	return filepath.Join(o.outputDir(o.InFilename), o.inputName(o.InFilename)+"-"+safeName+o.formatExtension())
+}
+
+// inputName returns the base name of the input file, without its extension
+// unless KeepExtension is set.
+func (o Options) inputName(input string) string {
+	name := filepath.Base(input)
+	if o.KeepExtension {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// formatExtension returns the filename extension of the image format, with
+// animated PNGs using the .png extension.
+func (o Options) formatExtension() string {
+	if o.ImageFormat == "apng" {
+		return ".png"
+	}
+	return "." + o.ImageFormat
+}
+
+// outputDir returns the output directory, or when not set the input file directory.
+func (o Options) outputDir(input string) string {
+	if len(o.OutputDir) > 0 {
+		return o.OutputDir
+	}
+	return filepath.Dir(input)
+}
+
 func (o Options) Validate() error {
 	var validationErrors error
 
-	if len(o.InFilename) == 0 {
+	if len(o.InFilename) == 0 && len(o.ImgFilename) == 0 {
 		validationErrors = errors.Join(validationErrors, errors.New("SCR filename is missing"))
+	} else if len(o.InFilename) > 0 && len(o.ImgFilename) > 0 {
+		validationErrors = errors.Join(validationErrors, errors.New("only one of a SCR or image filename can be given"))
 	}
 	if err := o.validateFormat(); err != nil {
 		validationErrors = errors.Join(validationErrors, err)
 	}
+	if err := o.validateScreenMode(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateHiResColour(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
 	if err := o.validateScale(); err != nil {
 		validationErrors = errors.Join(validationErrors, err)
 	}
+	if err := o.validateFilter(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateAspect(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateCRT(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
 	if err := o.validateBorderColour(); err != nil {
 		validationErrors = errors.Join(validationErrors, err)
 	}
+	if err := o.validateBorderSize(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateBorderLines(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateWorkers(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateDither(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateFlash(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
+	if err := o.validateAnimation(); err != nil {
+		validationErrors = errors.Join(validationErrors, err)
+	}
 
 	return validationErrors
 }
 
 func (o Options) validateFormat() error {
 	switch o.ImageFormat {
-	case "auto", "png", "jpg", "gif":
+	case "auto", "png", "apng", "jpg", "gif":
 		return nil
 	default:
 		return errors.New("unsupported image format")
 	}
 }
 
+func (o Options) validateScreenMode() error {
+	switch o.ScreenMode {
+	case "", "standard", "hicolour", "hires":
+		return nil
+	default:
+		return errors.New("unsupported screen mode")
+	}
+}
+
+func (o Options) validateHiResColour() error {
+	if o.HiResInk && (o.HiResColour < 0 || o.HiResColour > 7) {
+		return errors.New("hi-res colour must be a ZX Spectrum INK value: 0 - 7")
+	}
+	return nil
+}
+
 func (o Options) validateBorderColour() error {
 	if o.BorderColour < 0 || o.BorderColour > 15 {
 		return errors.New("border must be a ZX Spectrum colour value: 0 - 15")
@@ -64,9 +206,160 @@ func (o Options) validateBorderColour() error {
 	return nil
 }
 
+func (o Options) validateBorderSize() error {
+	switch o.BorderSize {
+	case "", "normal", "full", "frame":
+		return nil
+	case "custom":
+		if o.BorderLeft < 0 || o.BorderRight < 0 || o.BorderTop < 0 || o.BorderBottom < 0 {
+			return errors.New("border sizes cannot be negative")
+		}
+		return nil
+	default:
+		return errors.New("unsupported border size")
+	}
+}
+
+func (o Options) validateBorderLines() error {
+	for _, colour := range o.BorderLines {
+		if colour < 0 || colour > 15 {
+			return errors.New("border line colours must be a ZX Spectrum colour value: 0 - 15")
+		}
+	}
+
+	switch o.BorderStripes {
+	case "", "none", "pilot", "data":
+	default:
+		return errors.New("unsupported border stripes, must be pilot or data")
+	}
+	if o.StripeRandomness < 0 || o.StripeRandomness > 1 {
+		return errors.New("border stripe randomness must be 0 - 1")
+	}
+	return nil
+}
+
+// MaxImageSize is the maximum width and height of an output image, in pixels.
+const MaxImageSize = 8192
+
 func (o Options) validateScale() error {
-	if o.Scale <= 0 || o.Scale > 4 {
-		return errors.New("invalid scale factor, must be 1-4")
+	if o.Scale <= 0 {
+		return errors.New("invalid scale factor, must be 1 or more")
+	}
+
+	// the size at scale 1, with the border sizes of the largest preset
+	width, height := 256, 192
+	switch {
+	case !o.WithBorder:
+	case o.BorderSize == "custom":
+		width += o.BorderLeft + o.BorderRight
+		height += o.BorderTop + o.BorderBottom
+	default:
+		width, height = 352, 312
+	}
+	if o.ScreenMode == "hires" {
+		width, height = width*2, height*2
+	}
+
+	if width*o.Scale > MaxImageSize || height*o.Scale > MaxImageSize {
+		return fmt.Errorf("scale factor too large, images can be at most %dx%d pixels", MaxImageSize, MaxImageSize)
+	}
+	return nil
+}
+
+func (o Options) validateFilter() error {
+	switch o.Filter {
+	case "", "none", "epx", "scalex", "xbr":
+	default:
+		return errors.New("unsupported upscaling filter")
+	}
+	if o.FitWidth < 0 || o.FitHeight < 0 {
+		return errors.New("fit width and height cannot be negative")
+	}
+	if o.FitWidth > MaxImageSize || o.FitHeight > MaxImageSize {
+		return fmt.Errorf("fit width and height can be at most %d", MaxImageSize)
+	}
+	return nil
+}
+
+func (o Options) validateAspect() error {
+	switch o.Aspect {
+	case "", "square", "pal":
+		return nil
+	default:
+		return errors.New("unsupported aspect ratio")
+	}
+}
+
+func (o Options) validateCRT() error {
+	switch o.CRT {
+	case "", "none", "scanlines", "monitor", "tv":
+		return nil
+	default:
+		return errors.New("unsupported CRT filter preset")
+	}
+}
+
+func (o Options) validateWorkers() error {
+	if o.Workers < 0 {
+		return errors.New("number of workers cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateDither() error {
+	switch o.Dither {
+	case "", "none", "bayer2", "bayer4", "bayer8", "floyd-steinberg", "atkinson":
+		return nil
+	default:
+		return errors.New("unsupported dithering method")
+	}
+}
+
+func (o Options) validateFlash() error {
+	if o.FlashDelay < 0 {
+		return errors.New("FLASH delay cannot be negative")
+	}
+	if o.FlashLoopCount < 0 {
+		return errors.New("FLASH loop count cannot be negative")
+	}
+	if o.FlashPhase < 0 || o.FlashPhase > 1 {
+		return errors.New("FLASH phase must be 0 or 1")
+	}
+	return nil
+}
+
+// isSCRFilename returns false for the tape and snapshot files, which are not
+// SCR data, while all other files, stdin, directories and glob patterns can be.
+func isSCRFilename(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".tap", ".tzx", ".sna", ".z80":
+		return false
+	}
+	return true
+}
+
+func (o Options) validateAnimation() error {
+	if o.Loading && o.Sequence {
+		return errors.New("only one of the loading or sequence animations can be selected")
+	}
+	if o.Loading && !isSCRFilename(o.InFilename) {
+		return errors.New("the loading animation can only be made from a SCR file")
+	}
+	if o.Loading || o.Sequence {
+		switch o.ImageFormat {
+		case "auto", "gif", "apng":
+		default:
+			return errors.New("animations must be a gif or apng format")
+		}
+	}
+	if o.LoadingSpeed < 0 {
+		return errors.New("loading speed cannot be negative")
+	}
+	if o.FrameDelay < 0 {
+		return errors.New("frame delay cannot be negative")
+	}
+	if o.LoopCount < 0 {
+		return errors.New("animation loop count cannot be negative")
 	}
 	return nil
 }
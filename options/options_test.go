@@ -41,6 +41,84 @@ func TestOptions_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("image filename validation", func(t *testing.T) {
+		defer func() {
+			opts.ImgFilename = "" // reset after use
+		}()
+
+		opts.ImgFilename = "/path/to/something.png"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error when both SCR and image filenames are given")
+		}
+		opts.InFilename = ""
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.InFilename = "/path/to/something.scr"
+	})
+
+	t.Run("dither validation", func(t *testing.T) {
+		defer func() {
+			opts.Dither = "" // reset after use
+		}()
+
+		tests := map[string]bool{
+			"": true, "none": true, "bayer2": true, "bayer4": true, "bayer8": true,
+			"floyd-steinberg": true, "atkinson": true, "bayer3": false, "random": false,
+		}
+		for method, valid := range tests {
+			opts.Dither = method
+			err := opts.Validate()
+			if valid && err != nil {
+				t.Errorf("unexpected validation error for %q, got %s", method, err)
+			} else if !valid && err == nil {
+				t.Errorf("expected a validation error for %q", method)
+			}
+		}
+	})
+
+	t.Run("screen mode validation", func(t *testing.T) {
+		defer func() {
+			opts.ScreenMode = "" // reset after use
+		}()
+
+		tests := map[string]bool{"": true, "standard": true, "hicolour": true, "hires": true, "ulaplus": false}
+		for mode, valid := range tests {
+			opts.ScreenMode = mode
+			err := opts.Validate()
+			if valid && err != nil {
+				t.Errorf("unexpected validation error for %q, got %s", mode, err)
+			} else if !valid && err == nil {
+				t.Errorf("expected a validation error for %q", mode)
+			}
+		}
+	})
+
+	t.Run("hi-res colour validation", func(t *testing.T) {
+		defer func() {
+			opts.HiResInk, opts.HiResColour = false, 0 // reset after use
+		}()
+
+		opts.HiResColour = -1
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error when not used, got %s", err)
+		}
+
+		opts.HiResInk = true
+		for colour := 0; colour <= 7; colour++ {
+			opts.HiResColour = colour
+			if err := opts.Validate(); err != nil {
+				t.Errorf("unexpected error, got %s", err)
+			}
+		}
+		for _, colour := range []int{-1, 8} {
+			opts.HiResColour = colour
+			if err := opts.Validate(); err == nil {
+				t.Errorf("expect and error")
+			}
+		}
+	})
+
 	t.Run("scale factor validation", func(t *testing.T) {
 		defer func() {
 			opts.Scale = 2 // reset after use
@@ -53,7 +131,183 @@ func TestOptions_Validate(t *testing.T) {
 		if err := opts.Validate(); err == nil {
 			t.Errorf("expect and error")
 		}
-		opts.Scale = 5
+		opts.Scale = 12
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.Scale = 1000
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("image size validation", func(t *testing.T) {
+		reset := func() {
+			opts.Scale, opts.ScreenMode, opts.WithBorder = 2, "", true
+			opts.BorderSize, opts.BorderLeft, opts.FitWidth = "", 0, 0
+		}
+		defer reset() // reset after use
+
+		tests := []struct {
+			name  string
+			setup func()
+			valid bool
+		}{
+			{"largest border", func() { opts.Scale = 23 }, true},
+			{"largest border too large", func() { opts.Scale = 24 }, false},
+			{"without a border", func() { opts.Scale, opts.WithBorder = 32, false }, true},
+			{"hi-res", func() { opts.Scale, opts.ScreenMode = 11, "hires" }, true},
+			{"hi-res too large", func() { opts.Scale, opts.ScreenMode = 12, "hires" }, false},
+			{"custom border", func() { opts.Scale, opts.BorderSize, opts.BorderLeft = 2, "custom", 4000 }, false},
+			{"fit width", func() { opts.FitWidth = 3840 }, true},
+			{"fit width too large", func() { opts.FitWidth = 10000 }, false},
+		}
+		for _, tc := range tests {
+			reset()
+			tc.setup()
+			err := opts.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("%s: unexpected error, got %s", tc.name, err)
+			} else if !tc.valid && err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+		}
+	})
+
+	t.Run("aspect validation", func(t *testing.T) {
+		defer func() {
+			opts.Aspect = "" // reset after use
+		}()
+
+		opts.Aspect = "pal"
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.Aspect = "ntsc"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("border size validation", func(t *testing.T) {
+		defer func() {
+			opts.BorderSize, opts.BorderLeft = "", 0 // reset after use
+		}()
+
+		for _, size := range []string{"normal", "full", "frame", "custom"} {
+			opts.BorderSize = size
+			if err := opts.Validate(); err != nil {
+				t.Errorf("unexpected error, got %s", err)
+			}
+		}
+		opts.BorderLeft = -8
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.BorderSize, opts.BorderLeft = "huge", 0
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("border lines validation", func(t *testing.T) {
+		defer func() {
+			opts.BorderLines, opts.BorderStripes, opts.StripeRandomness = nil, "", 0 // reset after use
+		}()
+
+		opts.BorderLines, opts.BorderStripes, opts.StripeRandomness = []int{0, 7, 15}, "data", 0.5
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.BorderLines = []int{16}
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.BorderLines, opts.BorderStripes = nil, "sync"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.BorderStripes, opts.StripeRandomness = "pilot", 1.5
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("animation validation", func(t *testing.T) {
+		defer func() {
+			opts.Loading, opts.Sequence, opts.LoadingSpeed, opts.FrameDelay, opts.LoopCount, opts.ImageFormat = false, false, 0, 0, 0, "png" // reset after use
+		}()
+
+		opts.Loading, opts.LoadingSpeed, opts.FrameDelay, opts.ImageFormat = true, 8, 4, "apng"
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		for _, input := range []string{"/path/to/game.tap", "/path/to/game.TZX", "/path/to/game.sna", "/path/to/game.z80"} {
+			opts.InFilename = input
+			if err := opts.Validate(); err == nil {
+				t.Errorf("%s: expect and error", input)
+			}
+		}
+		opts.InFilename = "/path/to/something.scr"
+		opts.ImageFormat = "jpg"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.ImageFormat, opts.LoadingSpeed = "gif", -1
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.LoadingSpeed, opts.FrameDelay = 8, -1
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.FrameDelay, opts.Sequence = 4, true
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.Loading = false
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.LoopCount = -1
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("CRT validation", func(t *testing.T) {
+		defer func() {
+			opts.CRT = "" // reset after use
+		}()
+
+		for _, preset := range []string{"none", "scanlines", "monitor", "tv"} {
+			opts.CRT = preset
+			if err := opts.Validate(); err != nil {
+				t.Errorf("unexpected error, got %s", err)
+			}
+		}
+		opts.CRT = "plasma"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
+
+	t.Run("filter validation", func(t *testing.T) {
+		defer func() {
+			opts.Filter, opts.FitWidth = "", 0 // reset after use
+		}()
+
+		for _, filter := range []string{"none", "epx", "scalex", "xbr"} {
+			opts.Filter = filter
+			if err := opts.Validate(); err != nil {
+				t.Errorf("unexpected error, got %s", err)
+			}
+		}
+		opts.Filter = "lanczos"
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.Filter, opts.FitWidth = "none", -1
 		if err := opts.Validate(); err == nil {
 			t.Errorf("expect and error")
 		}
@@ -81,6 +335,29 @@ func TestOptions_Validate(t *testing.T) {
 			t.Errorf("expect and error")
 		}
 	})
+
+	t.Run("FLASH validation", func(t *testing.T) {
+		defer func() {
+			opts.FlashDelay, opts.FlashLoopCount, opts.FlashPhase = 0, 0, 0 // reset after use
+		}()
+
+		opts.FlashDelay, opts.FlashLoopCount, opts.FlashPhase = 16, 3, 1
+		if err := opts.Validate(); err != nil {
+			t.Errorf("unexpected error, got %s", err)
+		}
+		opts.FlashDelay = -1
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.FlashDelay, opts.FlashLoopCount = 16, -1
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+		opts.FlashLoopCount, opts.FlashPhase = 3, 2
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expect and error")
+		}
+	})
 }
 
 func TestOptions_OutputFilename(t *testing.T) {
@@ -104,4 +381,66 @@ func TestOptions_OutputFilename(t *testing.T) {
 			t.Errorf("unexpected filename, got '%s'", filename)
 		}
 	})
+
+	t.Run("when the format is an animated PNG", func(t *testing.T) {
+		opts := options.Options{InFilename: "/path/to/something.scr", ImageFormat: "apng"}
+		if filename := opts.OutputFilename(); filename != "/path/to/something.png" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+	})
+
+	t.Run("when an output directory is given", func(t *testing.T) {
+		opts := options.Options{InFilename: "/path/to/something.scr", ImageFormat: "gif", OutputDir: "/output/to"}
+		filename := opts.OutputFilename()
+
+		if filename != "/output/to/something.gif" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+	})
+
+	t.Run("when an output filename is given", func(t *testing.T) {
+		opts := options.Options{InFilename: "/path/to/something.scr", ImageFormat: "gif", OutFilename: "/output/image.gif"}
+		if filename := opts.OutputFilename(); filename != "/output/image.gif" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+	})
+
+	t.Run("when reading from stdin", func(t *testing.T) {
+		opts := options.Options{InFilename: "-", ImageFormat: "png"}
+		if filename := opts.OutputFilename(); filename != "-" {
+			t.Errorf("expected stdout, got '%s'", filename)
+		}
+	})
+
+	t.Run("when converting an image to SCR", func(t *testing.T) {
+		opts := options.Options{ImgFilename: "/path/to/something.png"}
+		filename := opts.OutputFilename()
+
+		if filename != "/path/to/something.scr" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+	})
+}
+
+func TestOptions_OutputFilenameFor(t *testing.T) {
+	opts := options.Options{
+		InFilename:  "/path/to/game.tap",
+		ImageFormat: "gif",
+	}
+
+	filename := opts.OutputFilenameFor("Game/Scr 1")
+	if filename != "/path/to/game-Game_Scr_1.gif" {
+		t.Errorf("unexpected filename, got '%s'", filename)
+	}
+
+	t.Run("keep extension", func(t *testing.T) {
+		opts.KeepExtension = true
+		if filename := opts.OutputFilenameFor("title"); filename != "/path/to/game.tap-title.gif" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+		opts.InFilename = "/path/to/game.sna"
+		if filename := opts.OutputFilename(); filename != "/path/to/game.sna.gif" {
+			t.Errorf("unexpected filename, got '%s'", filename)
+		}
+	})
 }
@@ -0,0 +1,103 @@
+// Package scr registers the ZX Spectrum SCR format with the standard library
+// image package, so SCR data can be read using image.Decode.
+//
+// As SCR files have no magic number, the data must first be wrapped using
+// NewReader, which identifies SCR data by its size:
+//
+//	r, err := scr.NewReader(file)
+//	img, format, err := image.Decode(r)
+//
+// Decode and DecodeConfig can also be called directly with raw SCR data.
+package scr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	goImage "image"
+	"image/color"
+	"io"
+
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+// The header added to SCR data by NewReader, which can not be the start of
+// any other image format.
+const magic = "\x00ZX-SCR\x00"
+
+// The sizes of the supported SCR files: bitmap only, standard and ULAplus.
+const (
+	scrBitmapLength  = 6144
+	scrLength        = 6912
+	scrULAplusLength = 6976
+)
+
+func init() {
+	goImage.RegisterFormat("scr", magic, Decode, DecodeConfig)
+}
+
+// NewReader returns a reader to be used with image.Decode. Data with the
+// size of a bitmap only SCR (6144 bytes), SCR (6912 bytes) or ULAplus SCR
+// (6976 bytes) is prefixed with a header identifying it as a SCR, while all
+// other data is returned unchanged.
+func NewReader(r io.Reader) (io.Reader, error) {
+	buf := make([]byte, scrULAplusLength+1)
+	n, err := io.ReadFull(r, buf)
+
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF) && (n == scrBitmapLength || n == scrLength || n == scrULAplusLength):
+		return io.MultiReader(bytes.NewReader([]byte(magic)), bytes.NewReader(buf[:n])), nil
+	case err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF):
+		return nil, err
+	}
+
+	return io.MultiReader(bytes.NewReader(buf[:n]), r), nil
+}
+
+// Decode reads a SCR and returns it as a 256x192 image, without a border.
+func Decode(r io.Reader) (goImage.Image, error) {
+	data, err := readData(r)
+	if err != nil {
+		return nil, err
+	}
+	img, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// DecodeConfig returns the colour model and dimensions of a SCR, without
+// decoding the entire image. The dimensions are always 256x192, with the
+// colour model being the default palette, or the ULAplus palette.
+func DecodeConfig(r io.Reader) (goImage.Config, error) {
+	data, err := readData(r)
+	if err != nil {
+		return goImage.Config{}, err
+	}
+
+	var colours color.Palette
+	switch len(data) {
+	case scrBitmapLength, scrLength:
+		palette, _ := image.PaletteByName("default")
+		colours = palette.Colors()
+	case scrULAplusLength:
+		var palette image.ULAplusPalette
+		copy(palette[:], data[scrLength:])
+		colours = palette.Colors()
+	default:
+		return goImage.Config{}, fmt.Errorf("invalid SCR size of %d bytes", len(data))
+	}
+
+	return goImage.Config{ColorModel: colours, Width: 256, Height: 192}, nil
+}
+
+// readData reads all the SCR data, removing the NewReader header if present.
+func readData(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(data, []byte(magic)), nil
+}
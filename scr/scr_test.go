@@ -0,0 +1,108 @@
+package scr_test
+
+import (
+	"bytes"
+	goImage "image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/mrcook/scrconv/scr"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 6912)
+	data[0] = 0b10000000
+	data[6144] = 0b00000010 // red ink
+
+	r, err := scr.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	img, format, err := goImage.Decode(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if format != "scr" {
+		t.Errorf("expected the scr format, got %s", format)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 256 || bounds.Dy() != 192 {
+		t.Errorf("expected a 256x192 image, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	if red, g, b, _ := img.At(0, 0).RGBA(); red != 0xEEEE || g != 0 || b != 0 {
+		t.Errorf("expected a red pixel, got: %04X, %04X, %04X", red, g, b)
+	}
+
+	t.Run("decode without the reader wrapper", func(t *testing.T) {
+		img, err := scr.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if img.Bounds().Dx() != 256 {
+			t.Errorf("unexpected image width, got %d", img.Bounds().Dx())
+		}
+	})
+
+	t.Run("decode config", func(t *testing.T) {
+		r, _ := scr.NewReader(bytes.NewReader(data))
+		config, format, err := goImage.DecodeConfig(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if format != "scr" || config.Width != 256 || config.Height != 192 {
+			t.Errorf("unexpected config: %s %dx%d", format, config.Width, config.Height)
+		}
+		if _, ok := config.ColorModel.(color.Palette); !ok {
+			t.Errorf("expected a palette colour model")
+		}
+	})
+	t.Run("decode config ULAplus", func(t *testing.T) {
+		config, err := scr.DecodeConfig(bytes.NewReader(make([]byte, 6976)))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if palette, ok := config.ColorModel.(color.Palette); !ok || len(palette) != 64 {
+			t.Errorf("expected a 64 colour palette")
+		}
+	})
+
+	t.Run("bitmap only", func(t *testing.T) {
+		r, err := scr.NewReader(bytes.NewReader(data[:6144]))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		img, format, err := goImage.Decode(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if format != "scr" || img.Bounds().Dx() != 256 || img.Bounds().Dy() != 192 {
+			t.Errorf("unexpected image: %s %dx%d", format, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+	})
+
+	t.Run("decode config invalid size", func(t *testing.T) {
+		if _, err := scr.DecodeConfig(bytes.NewReader(make([]byte, 100))); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestNewReader_OtherFormats(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, goImage.NewGray(goImage.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, err := scr.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, format, err := goImage.Decode(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if format != "png" {
+		t.Errorf("expected the png format, got %s", format)
+	}
+}
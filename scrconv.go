@@ -1,47 +1,168 @@
 package scrconv
 
 import (
+	"bytes"
 	goImage "image"
-	"image/draw"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"path/filepath"
+	"strings"
 
 	"github.com/mrcook/scrconv/image"
 	"github.com/mrcook/scrconv/options"
 )
 
-// ConvertToImage reads the data from a SCR file and converts it to the image data.
+// ConvertToImage reads the data from a SCR file and converts it to the image data,
+// using the decoder for the selected screen mode. Snapshot files are detected
+// by the input filename extension, with their screen being converted.
 func ConvertToImage(file io.Reader, opts options.Options) (*image.Image, error) {
-	return image.FromSCR(file, opts)
+	switch strings.ToLower(filepath.Ext(opts.InFilename)) {
+	case ".sna":
+		return image.FromSNA(file, opts)
+	case ".z80":
+		return image.FromZ80(file, opts)
+	}
+
+	switch opts.ScreenMode {
+	case "hicolour":
+		return image.FromHiColour(file, opts)
+	case "hires":
+		return image.FromHiRes(file, opts)
+	default:
+		return image.FromSCR(file, opts)
+	}
+}
+
+// SCRStats reads the data from a SCR file and returns the details of its contents.
+func SCRStats(file io.Reader) (*image.Stats, error) {
+	return image.SCRStats(file)
+}
+
+// ImageToSCR reads a PNG, GIF or JPG image and converts it to the 6912 bytes
+// of a ZX Spectrum SCR file.
+func ImageToSCR(file io.Reader, opts options.Options) ([]byte, error) {
+	src, _, err := goImage.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return image.ToSCR(src, opts)
+}
+
+// ConvertTapeToImages reads the blocks of a .TAP or .TZX tape image, as
+// given by the input filename extension, converting each screen found to
+// the image data.
+func ConvertTapeToImages(file io.Reader, opts options.Options) ([]image.TapeScreen, error) {
+	switch strings.ToLower(filepath.Ext(opts.InFilename)) {
+	case ".tzx":
+		return image.FromTZX(file, opts)
+	default:
+		return image.FromTAP(file, opts)
+	}
+}
+
+// LoadingAnimation reads the data from a SCR file, returning the frames of an
+// animation of the screen being loaded from tape.
+func LoadingAnimation(file io.Reader, opts options.Options) ([]image.Frame, error) {
+	return image.LoadingAnimation(file, opts)
+}
+
+// SequenceAnimation converts the data of the SCR files, returning the frames
+// of an animation showing each screen in order.
+func SequenceAnimation(files [][]byte, opts options.Options) ([]image.Frame, error) {
+	return image.SequenceAnimation(files, opts)
+}
+
+// AnimationToGIF encodes the frames as an animated GIF, played the given
+// number of times, 0 to loop forever.
+func AnimationToGIF(w io.Writer, frames []image.Frame, plays int) error {
+	gifImages := &gif.GIF{LoopCount: gifLoopCount(plays)}
+	for _, frame := range frames {
+		gifImages.Image = append(gifImages.Image, frame.Image.RenderPaletted())
+		gifImages.Delay = append(gifImages.Delay, frame.Delay)
+	}
+	return gif.EncodeAll(w, gifImages)
 }
 
+// ImageToPNG encodes the image as a PNG, with any text info of the image
+// stored in tEXt chunks.
 func ImageToPNG(w io.Writer, img *image.Image) error {
-	return png.Encode(w, img)
+	if len(img.Info()) == 0 {
+		return png.Encode(w, img.Render())
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img.Render()); err != nil {
+		return err
+	}
+	_, err := w.Write(pngWithInfo(buf.Bytes(), img.Info()))
+	return err
 }
 
+// ImageToJPG encodes the image as a JPG, with any text info of the image
+// stored in a comment.
 func ImageToJPG(w io.Writer, img *image.Image, quality int) error {
-	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	if len(img.Info()) == 0 {
+		return jpeg.Encode(w, img.Render(), &jpeg.Options{Quality: quality})
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img.Render(), &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	_, err := w.Write(jpgWithInfo(buf.Bytes(), img.Info()))
+	return err
 }
 
+// ImageToGIF encodes the image as a GIF, which is animated when the image
+// has FLASH attributes, unless a static FLASH phase was selected. Any text
+// info of the image is stored in a comment.
 func ImageToGIF(w io.Writer, img *image.Image) error {
-	if !img.HasFlashingPixels() {
-		return gif.Encode(w, img, nil)
+	if len(img.Info()) == 0 {
+		return encodeGIF(w, img)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeGIF(&buf, img); err != nil {
+		return err
+	}
+	data, err := gifWithInfo(buf.Bytes(), img.Info())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func encodeGIF(w io.Writer, img *image.Image) error {
+	if !img.IsAnimated() {
+		return gif.Encode(w, img.RenderPaletted(), nil)
 	}
 
 	gifImages := &gif.GIF{
-		Delay:     []int{64, 64}, // 0.64 of a second
-		LoopCount: 0,             // infinite loop
+		Delay:     []int{img.FlashDelay(), img.FlashDelay()},
+		LoopCount: gifLoopCount(img.FlashLoopCount()),
 	}
 
 	// generate the base and FLASH enabled images
 	for _, state := range []bool{false, true} {
 		img.SetFlashOutput(state)
-		gifImage := goImage.NewPaletted(img.Bounds(), image.SpectrumPalette())
-		draw.Draw(gifImage, img.Bounds(), img, goImage.Point{}, draw.Src)
-		gifImages.Image = append(gifImages.Image, gifImage)
+		gifImages.Image = append(gifImages.Image, img.RenderPaletted())
 	}
 
 	return gif.EncodeAll(w, gifImages)
 }
+
+// gifLoopCount converts the number of times an animation is played to the
+// GIF loop count, which is the number of repeats: -1 to play once, 0 forever.
+func gifLoopCount(plays int) int {
+	switch {
+	case plays <= 0:
+		return 0
+	case plays == 1:
+		return -1
+	default:
+		return plays - 1
+	}
+}
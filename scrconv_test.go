@@ -0,0 +1,336 @@
+package scrconv_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	goImage "image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/mrcook/scrconv"
+	"github.com/mrcook/scrconv/image"
+	"github.com/mrcook/scrconv/options"
+)
+
+func flashingSCR() []byte {
+	data := make([]byte, 6912)
+	data[0] = 0b11110000
+	data[6144] = 0b10000010 // FLASH, red ink
+	return data
+}
+
+func TestImageToGIF_Flash(t *testing.T) {
+	t.Run("animated", func(t *testing.T) {
+		opts := options.Options{Scale: 1, FlashDelay: 16, FlashLoopCount: 3}
+		img, err := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToGIF(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("invalid GIF: %s", err)
+		}
+		if len(anim.Image) != 2 {
+			t.Fatalf("expected 2 frames, got %d", len(anim.Image))
+		}
+		if anim.Delay[0] != 16 || anim.Delay[1] != 16 {
+			t.Errorf("expected a delay of 16, got %v", anim.Delay)
+		}
+		if anim.LoopCount != 2 {
+			t.Errorf("expected a loop count of 2, got %d", anim.LoopCount)
+		}
+	})
+
+	t.Run("played once", func(t *testing.T) {
+		opts := options.Options{Scale: 1, FlashLoopCount: 1}
+		img, _ := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToGIF(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("invalid GIF: %s", err)
+		}
+		if anim.LoopCount != -1 {
+			t.Errorf("expected a loop count of -1, got %d", anim.LoopCount)
+		}
+	})
+
+	t.Run("default timing", func(t *testing.T) {
+		img, _ := image.FromSCR(bytes.NewReader(flashingSCR()), options.Options{Scale: 1})
+		if img.FlashDelay() != 32 {
+			t.Errorf("expected a delay of 32, got %d", img.FlashDelay())
+		}
+	})
+
+	t.Run("static phase", func(t *testing.T) {
+		for phase, want := range []uint32{0xEEEE, 0x0000} {
+			opts := options.Options{Scale: 1, FlashStatic: true, FlashPhase: phase}
+			img, err := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if img.IsAnimated() {
+				t.Errorf("expected a static image")
+			}
+
+			var buf bytes.Buffer
+			if err := scrconv.ImageToGIF(&buf, img); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			anim, err := gif.DecodeAll(&buf)
+			if err != nil {
+				t.Fatalf("invalid GIF: %s", err)
+			}
+			if len(anim.Image) != 1 {
+				t.Errorf("expected 1 frame, got %d", len(anim.Image))
+			}
+			if r, _, _, _ := anim.Image[0].At(0, 0).RGBA(); r != want {
+				t.Errorf("phase %d: expected red %04X, got %04X", phase, want, r)
+			}
+		}
+	})
+}
+
+func TestImageToAPNG(t *testing.T) {
+	t.Run("animated", func(t *testing.T) {
+		opts := options.Options{Scale: 1, FlashLoopCount: 2}
+		img, err := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		img.SetInfo([]image.TextInfo{{Key: "Title", Value: "Manic Miner"}})
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToAPNG(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		data := buf.Bytes()
+
+		// acTL: 2 frames, played twice
+		if !bytes.Contains(data, []byte("acTL\x00\x00\x00\x02\x00\x00\x00\x02")) {
+			t.Errorf("expected an acTL chunk")
+		}
+		if n := bytes.Count(data, []byte("fcTL")); n != 2 {
+			t.Errorf("expected 2 fcTL chunks, got %d", n)
+		}
+		if !bytes.Contains(data, []byte("fdAT\x00\x00\x00\x02")) {
+			t.Errorf("expected an fdAT chunk with sequence number 2")
+		}
+		if !bytes.Contains(data, []byte("tEXtTitle\x00Manic Miner")) {
+			t.Errorf("expected a tEXt chunk with the title")
+		}
+
+		// the default image is readable by a plain PNG decoder
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("invalid PNG: %s", err)
+		}
+		if decoded.Bounds() != img.Bounds() {
+			t.Errorf("unexpected bounds, got %v", decoded.Bounds())
+		}
+	})
+
+	t.Run("without FLASH", func(t *testing.T) {
+		img, err := image.FromSCR(bytes.NewReader(make([]byte, 6912)), options.Options{Scale: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToAPNG(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bytes.Contains(buf.Bytes(), []byte("acTL")) {
+			t.Errorf("expected a plain PNG")
+		}
+	})
+}
+
+func TestAnimation(t *testing.T) {
+	var frames []image.Frame
+	for i, data := range [][]byte{make([]byte, 6912), flashingSCR()} {
+		img, err := image.FromSCR(bytes.NewReader(data), options.Options{Scale: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		frames = append(frames, image.Frame{Image: img, Delay: 10 * (i + 1)})
+	}
+
+	t.Run("GIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := scrconv.AnimationToGIF(&buf, frames, 1); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("invalid GIF: %s", err)
+		}
+		if len(anim.Image) != 2 || anim.Delay[0] != 10 || anim.Delay[1] != 20 {
+			t.Errorf("unexpected frames, got %d with delays %v", len(anim.Image), anim.Delay)
+		}
+		if anim.LoopCount != -1 {
+			t.Errorf("expected to play once, got loop count %d", anim.LoopCount)
+		}
+	})
+
+	t.Run("APNG", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := scrconv.AnimationToAPNG(&buf, frames, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("acTL\x00\x00\x00\x02\x00\x00\x00\x00")) {
+			t.Errorf("expected an acTL chunk with 2 frames")
+		}
+		if _, err := png.Decode(&buf); err != nil {
+			t.Errorf("invalid PNG: %s", err)
+		}
+	})
+}
+
+func TestImageToGIF_Filtered(t *testing.T) {
+	t.Run("EPX keeps the palette colours", func(t *testing.T) {
+		opts := options.Options{Scale: 2, Filter: "epx", FlashStatic: true}
+		img, err := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToGIF(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		out, err := gif.Decode(&buf)
+		if err != nil {
+			t.Fatalf("invalid GIF: %s", err)
+		}
+		if r, g, b, _ := out.At(0, 0).RGBA(); r != 0xEEEE || g != 0 || b != 0 {
+			t.Errorf("expected a 0xEE red pixel, got: %04X, %04X, %04X", r, g, b)
+		}
+	})
+
+	t.Run("CRT colours are reduced to 256", func(t *testing.T) {
+		opts := options.Options{Scale: 3, CRT: "tv", WithBorder: true, BorderColour: 1, FlashStatic: true}
+		img, err := image.FromSCR(bytes.NewReader(flashingSCR()), opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if err := scrconv.ImageToGIF(&buf, img); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		out, err := gif.Decode(&buf)
+		if err != nil {
+			t.Fatalf("invalid GIF: %s", err)
+		}
+		if palette := out.ColorModel().(color.Palette); len(palette) > 256 || len(palette) < 16 {
+			t.Errorf("expected a palette of the used colours, got %d", len(palette))
+		}
+	})
+}
+
+// apngFrames decodes each frame of an animated PNG, returning the frame
+// images and their delays. Each frame is rebuilt as a PNG from the chunks of
+// the APNG, with its fdAT chunks changed back to IDAT chunks.
+func apngFrames(t *testing.T, data []byte) ([]goImage.Image, []int) {
+	t.Helper()
+
+	chunk := func(chunkType string, data []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(out, chunkType...)
+		out = append(out, data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+	}
+
+	var header []byte // the signature, IHDR and PLTE
+	var frames [][]byte
+	var delays []int
+
+	header = append(header, data[:8]...)
+	for data = data[8:]; len(data) >= 12; {
+		length := int(binary.BigEndian.Uint32(data))
+		chunkType, body := string(data[4:8]), data[8:8+length]
+		data = data[12+length:]
+
+		switch chunkType {
+		case "IHDR", "PLTE":
+			header = append(header, chunk(chunkType, body)...)
+		case "fcTL":
+			frames = append(frames, nil)
+			delays = append(delays, int(binary.BigEndian.Uint16(body[20:])))
+		case "IDAT":
+			frames[len(frames)-1] = append(frames[len(frames)-1], chunk("IDAT", body)...)
+		case "fdAT":
+			frames[len(frames)-1] = append(frames[len(frames)-1], chunk("IDAT", body[4:])...)
+		}
+	}
+
+	var images []goImage.Image
+	for i, frame := range frames {
+		frameData := append(append(append([]byte{}, header...), frame...), chunk("IEND", nil)...)
+		img, err := png.Decode(bytes.NewReader(frameData))
+		if err != nil {
+			t.Fatalf("invalid APNG frame %d: %s", i+1, err)
+		}
+		images = append(images, img)
+	}
+	return images, delays
+}
+
+func TestAnimationToAPNG_MixedFrames(t *testing.T) {
+	// ulaplusSCR returns a ULAplus screen with ink 0 of CLUT group 0 set to
+	// the GGGRRRBB colour, with all pixels set.
+	ulaplusSCR := func(colour byte) []byte {
+		data := make([]byte, 6976)
+		for i := 0; i < 6144; i++ {
+			data[i] = 0xFF
+		}
+		data[6912] = colour
+		return data
+	}
+	red := flashingSCR()
+	red[6144] = 0b00000010 // no FLASH, red ink
+
+	opts := options.Options{Scale: 1, FrameDelay: 10}
+	frames, err := scrconv.SequenceAnimation([][]byte{red, ulaplusSCR(0b00011100), ulaplusSCR(0b11100000)}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	frames[2].Delay = 100000
+
+	var buf bytes.Buffer
+	if err := scrconv.AnimationToAPNG(&buf, frames, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	images, delays := apngFrames(t, buf.Bytes())
+	if len(images) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(images))
+	}
+	if delays[0] != 10 || delays[2] != 0xFFFF {
+		t.Errorf("expected delays of 10 and the 65535 maximum, got %v", delays)
+	}
+
+	expected := []color.RGBA{
+		{R: 0xEE, A: 0xFF}, // the red ink of the SCR
+		{R: 0xFF, A: 0xFF}, // ULAplus red
+		{G: 0xFF, A: 0xFF}, // ULAplus green
+	}
+	for i, img := range images {
+		r, g, b, _ := img.At(0, 0).RGBA()
+		if c := expected[i]; r>>8 != uint32(c.R) || g>>8 != uint32(c.G) || b>>8 != uint32(c.B) {
+			t.Errorf("frame %d: expected %v, got: %04X, %04X, %04X", i+1, c, r, g, b)
+		}
+	}
+}